@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/ddl"
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/fatih/color"
+)
+
+// writeViewAlterations writes views in the same layout as -show-create -algo
+// output, which is what view-formatter reads
+func writeViewAlterations(w io.Writer, server, database string, views []ddl.ViewChange) error {
+	if _, err := fmt.Fprintf(w, "Source server: %s, Database: %s\n\n", server, database); err != nil {
+		return err
+	}
+	for _, view := range views {
+		_, err := fmt.Fprintf(w, "Processing view: %s\nObject: %s\nType: VIEW\nCreate Statement:\n%s\n%s\n%s\nALTER VIEW Statement:\n%s\n%s\n%s\n\n",
+			view.Name, view.Name,
+			strings.Repeat("-", 80), view.CreateStatement, strings.Repeat("-", 80),
+			strings.Repeat("-", 80), view.AlterStatement, strings.Repeat("-", 80))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeViews writes the selected views as text for the terminal, or as
+// records in the documented view export format with -o json or ndjson
+func writeViews(w io.Writer, server, database, algorithm string, views []ddl.ViewChange) error {
+	if isStructuredOutput() {
+		return writeRecords(w, *outputFormat, viewColumns, viewRows(server, database, algorithm, views))
+	}
+	return writeViewAlterations(w, server, database, views)
+}
+
+// Handle algorithm changes for every selected view in a database
+func handleBulkViewAlgorithm(ctx context.Context, db *sql.DB, server, database, algorithm string, filter inventory.Filter, execute bool) error {
+	// Validate algorithm
+	algorithm, err := ddl.ValidateAlgorithm(algorithm)
+	if err != nil {
+		return err
+	}
+
+	views, err := ddl.SelectViews(ctx, db, database, algorithm, ddl.ViewSelector{
+		Filter:     filter,
+		Algorithms: strings.Split(*currentAlgorithm, ","),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Progress goes to stderr when stdout carries the export
+	status := statusOutput()
+	if len(views) == 0 {
+		fmt.Fprintln(status, "No views selected")
+		if isStructuredOutput() && *algoOut == "" {
+			return writeViews(os.Stdout, server, database, algorithm, views)
+		}
+		return nil
+	}
+
+	// Write the statements to a file when asked, otherwise print them
+	if *algoOut != "" {
+		file, err := os.Create(*algoOut)
+		if err != nil {
+			return err
+		}
+		if err := writeViews(file, server, database, algorithm, views); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(status, "Wrote %d ALTER VIEW statements to %s\n", len(views), *algoOut)
+	} else if err := writeViews(os.Stdout, server, database, algorithm, views); err != nil {
+		return err
+	}
+
+	// MySQL accepts MERGE for any view, but only stores it for views it can merge
+	unmergeable := 0
+	if algorithm == "MERGE" {
+		for _, view := range views {
+			if len(view.MergeBlockers) > 0 {
+				unmergeable++
+				fmt.Fprintf(status, "%s: MySQL cannot merge %s because of %s\n",
+					color.YellowString("Warning"), view.Name, strings.Join(view.MergeBlockers, ", "))
+			}
+		}
+	}
+
+	if !execute {
+		fmt.Fprintf(status, "%s: %d\n", color.YellowString("Views selected"), len(views))
+		if unmergeable > 0 {
+			fmt.Fprintf(status, "%s: %d, MySQL stores ALGORITHM=UNDEFINED for them\n", color.YellowString("Cannot be merged"), unmergeable)
+		}
+		return nil
+	}
+
+	// If execute flag is set, execute every ALTER VIEW statement, check the
+	// algorithm each view ended up with, and summarize
+	results := make([]ddl.AlgorithmResult, len(views))
+	failed, notApplied := 0, 0
+	for i := range views {
+		fmt.Fprintf(status, "Executing ALTER VIEW for %s... ", views[i].Name)
+		results[i], views[i].Err = ddl.ApplyViewAlgorithm(ctx, db, database, views[i].Name, algorithm, views[i].AlterStatement)
+		switch {
+		case views[i].Err != nil:
+			failed++
+			fmt.Fprintf(status, "%s\n", color.RedString("Failed"))
+		case !results[i].Honoured():
+			notApplied++
+			fmt.Fprintf(status, "%s\n", color.YellowString("Not applied"))
+		default:
+			fmt.Fprintf(status, "%s\n", color.GreenString("Success"))
+		}
+	}
+
+	fmt.Fprintln(status)
+	table := newTableWriter(status, []string{"View", "From", "To", "Effective", "Result", "Message"})
+	for i, view := range views {
+		result, message := color.GreenString("OK"), strings.Join(results[i].Warnings, "; ")
+		switch {
+		case view.Err != nil:
+			result, message = color.RedString("FAILED"), view.Err.Error()
+		case !results[i].Honoured():
+			result = color.YellowString("NOT APPLIED")
+		}
+		table.Append([]string{view.Name, view.Algorithm, algorithm, results[i].Effective, result, message})
+	}
+	table.Render()
+	fmt.Fprintln(status)
+	fmt.Fprintf(status, "%s: %d, %s: %d, %s: %d\n", color.GreenString("Succeeded"), len(views)-failed-notApplied,
+		color.YellowString("Not applied"), notApplied, color.RedString("Failed"), failed)
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d ALTER VIEW statements failed", failed, len(views))
+	}
+	if notApplied > 0 {
+		return fmt.Errorf("%d of %d views did not get ALGORITHM=%s", notApplied, len(views), algorithm)
+	}
+	return nil
+}
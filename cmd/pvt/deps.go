@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/deps"
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/fatih/color"
+)
+
+// depsRoots returns the objects the graph is shown from: the objects named
+// name, narrowed by -type, or else the objects of schemas that pass filter
+func depsRoots(ctx context.Context, g *deps.Graph, db *sql.DB, schemas []string, name string, filter inventory.Filter) ([]deps.Node, error) {
+	if name != "" {
+		schema := schemas[0]
+		if s, n, ok := strings.Cut(name, "."); ok {
+			schema, name = s, n
+		}
+		var roots []deps.Node
+		for _, n := range g.Find(schema, name) {
+			if len(filter.Types) == 0 || filter.HasType(n.Type) {
+				roots = append(roots, n)
+			}
+		}
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("no object named %s in %s", name, schema)
+		}
+		return roots, nil
+	}
+
+	var roots []deps.Node
+	for _, schema := range schemas {
+		objects, err := inventory.ObjectsMatching(ctx, db, schema, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objects {
+			if n, ok := g.Lookup(o.Schema, o.Name, o.Type); ok {
+				roots = append(roots, n)
+			}
+		}
+	}
+	return roots, nil
+}
+
+// nodeStatus describes whether a node was found for the uses_status key
+func nodeStatus(n deps.Node) string {
+	switch {
+	case n.Missing:
+		return "missing"
+	case n.External:
+		return "external"
+	}
+	return "found"
+}
+
+// depsRows converts edges to rows in depsColumns order
+func depsRows(host string, edges []deps.Edge) [][]string {
+	rows := make([][]string, len(edges))
+	for i, e := range edges {
+		rows[i] = []string{host, e.From.Schema, e.From.Name, e.From.Type, e.To.Schema, e.To.Name, e.To.Type, nodeStatus(e.To)}
+	}
+	return rows
+}
+
+// orderRows converts ordered nodes to rows in orderColumns order
+func orderRows(host string, nodes []deps.Node) [][]string {
+	rows := make([][]string, len(nodes))
+	for i, n := range nodes {
+		rows[i] = []string{host, strconv.Itoa(i + 1), n.Schema, n.Name, n.Type}
+	}
+	return rows
+}
+
+// runDeps shows which objects use which: as a tree, DOT or records, what
+// depends on an object with -dependents, and the order to create objects in
+// with -order
+func runDeps(ctx context.Context) int {
+	if flag.NArg() != 1 && flag.NArg() != 2 {
+		flag.Usage()
+		return 1
+	}
+	conn, err := loadConnection()
+	if err != nil {
+		fatal(err)
+	}
+	*outputFormat, err = validateOutputFormat(*outputFormat)
+	if err != nil {
+		fatal(err)
+	}
+	if *dot && (*order || isStructuredOutput()) {
+		fatalf("-dot cannot be combined with -order or -o %s", *outputFormat)
+	}
+	filter, err := parseFilter()
+	if err != nil {
+		fatal(err)
+	}
+	host, schemaList, err := parseEndpoint(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	schemas := strings.Split(schemaList, ",")
+
+	db, err := connectToDatabase(ctx, conn, host, schemas[0])
+	if err != nil {
+		fatal(err)
+	}
+	defer db.Close()
+
+	g, err := deps.Load(ctx, db, schemas)
+	if err != nil {
+		fatal(err)
+	}
+	roots, err := depsRoots(ctx, g, db, schemas, flag.Arg(1), filter)
+	if err != nil {
+		fatal(err)
+	}
+	if len(roots) == 0 {
+		fmt.Fprintln(statusOutput(), "No objects")
+		return 0
+	}
+
+	if *order {
+		selected := roots
+		if *dependents {
+			selected = g.Reachable(roots, true)
+		}
+		ordered, err := g.Order(selected)
+		if err != nil {
+			fatal(err)
+		}
+		if isStructuredOutput() {
+			if err := writeRecords(os.Stdout, *outputFormat, orderColumns, orderRows(host, ordered)); err != nil {
+				fatal(err)
+			}
+			return 0
+		}
+		fmt.Println(color.New(color.FgGreen).Sprint("Creation order:"))
+		table := newTable([]string{"#", "Schema", "Name", "Type"})
+		for _, row := range orderRows(host, ordered) {
+			table.Append(row[1:])
+		}
+		table.Render()
+		return 0
+	}
+
+	reached := g.Reachable(roots, *dependents)
+	switch {
+	case *dot:
+		err = deps.WriteDOT(os.Stdout, g, reached)
+	case isStructuredOutput():
+		err = writeRecords(os.Stdout, *outputFormat, depsColumns, depsRows(host, g.Edges(reached)))
+	default:
+		err = deps.WriteTree(os.Stdout, g, roots, *dependents)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/fatih/color"
+)
+
+// Labels of the -detail columns in the vertical layout
+var detailLabels = []string{"Host", "Schema", "Name", "Type", "Definer", "Parameters", "Returns", "Security",
+	"Deterministic", "Data access", "SQL mode", "Created", "Last altered", "Comment",
+	"Table", "Timing", "Event", "Action order", "References",
+	"Status", "Schedule", "Interval", "Starts", "Ends", "Last executed", "On completion",
+	"Engine", "Row format", "Rows", "Data length", "Index length", "Auto increment", "Collation"}
+
+// printDetails prints every object vertically like the mysql client's \G,
+// since the detail columns are too wide for one table. Empty values are left out.
+func printDetails(objects []inventory.Object) {
+	fmt.Printf("%s: %d\n", color.YellowString("Total"), len(objects))
+	fmt.Println()
+
+	width := 0
+	for _, label := range detailLabels {
+		width = max(width, len(label))
+	}
+	for i, row := range detailRows(objects) {
+		fmt.Println(color.GreenString("%s %d. %s %s", strings.Repeat("*", 27), i+1, objects[i].Type, strings.Repeat("*", 27)))
+		for j, value := range row {
+			if value == "" {
+				continue
+			}
+			// Events that will not run stand out
+			if detailLabels[j] == "Status" && (value == inventory.EventDisabled || inventory.ReplicaSideDisabled(value)) {
+				value = color.YellowString(value)
+			}
+			fmt.Printf("%*s: %s\n", width, detailLabels[j], value)
+		}
+	}
+}
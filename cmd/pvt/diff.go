@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/connect"
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/ChaosHour/go-pvt/pkg/schemadiff"
+	"github.com/fatih/color"
+)
+
+// parseEndpoint splits a HOST/SCHEMA argument. Without a host, -s is used.
+func parseEndpoint(arg string) (string, string, error) {
+	host, schema, found := strings.Cut(arg, "/")
+	if !found {
+		host, schema = *source, arg
+	}
+	if host == "" || schema == "" {
+		return "", "", fmt.Errorf("invalid schema %q, expected HOST/SCHEMA, or SCHEMA with -s", arg)
+	}
+	return host, schema, nil
+}
+
+// loadSide connects to one side of a comparison and reads its objects
+func loadSide(ctx context.Context, conn connect.Config, host, schema string, filter inventory.Filter) ([]schemadiff.Object, error) {
+	db, err := connectToDatabase(ctx, conn, host, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return schemadiff.Load(ctx, db, schema, filter)
+}
+
+// printDiff prints the differences for the terminal, with the unified diff
+// of every changed object, and counts them by statuses. The heading and the
+// message when nothing differs are status messages, so -q keeps a clean run
+// silent.
+func printDiff(from, to string, diffs []schemadiff.Difference, statuses []string) {
+	fmt.Fprintf(statusOutput(), "%s %s %s %s\n\n", color.YellowString("Comparing"), from, color.YellowString("with"), to)
+	if len(diffs) == 0 {
+		fmt.Fprintln(statusOutput(), color.GreenString("No differences"))
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, d := range diffs {
+		counts[d.Status]++
+		status := color.RedString("%-16s", d.Status)
+		fmt.Printf("%s %-10s %s\n", status, d.Type, d.Name)
+		if d.Status == schemadiff.DefinerChanged {
+			fmt.Printf("%16s  %s -> %s\n", "", d.Source.Definer, d.Target.Definer)
+		}
+		if d.Diff == "" {
+			continue
+		}
+		fmt.Println()
+		for _, line := range strings.Split(strings.TrimSuffix(d.Diff, "\n"), "\n") {
+			switch {
+			case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+				line = color.New(color.Bold).Sprint(line)
+			case strings.HasPrefix(line, "@@"):
+				line = color.CyanString(line)
+			case strings.HasPrefix(line, "-"):
+				line = color.RedString(line)
+			case strings.HasPrefix(line, "+"):
+				line = color.GreenString(line)
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+
+	summary := make([]string, len(statuses))
+	for i, status := range statuses {
+		summary[i] = fmt.Sprintf("%s: %d", color.YellowString(statusLabel(status)), counts[status])
+	}
+	fmt.Printf("\n%s\n", strings.Join(summary, ", "))
+}
+
+// statusLabel turns a status such as DEFINER_CHANGED into "Definer changed"
+func statusLabel(status string) string {
+	label := strings.ToLower(strings.ReplaceAll(status, "_", " "))
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// diffRows converts differences to rows in diffColumns order
+func diffRows(from, to string, diffs []schemadiff.Difference) [][]string {
+	rows := make([][]string, len(diffs))
+	for i, d := range diffs {
+		var sourceDefiner, targetDefiner string
+		if d.Source != nil {
+			sourceDefiner = d.Source.Definer
+		}
+		if d.Target != nil {
+			targetDefiner = d.Target.Definer
+		}
+		rows[i] = []string{from, to, d.Type, d.Name, d.Status, sourceDefiner, targetDefiner, d.Diff}
+	}
+	return rows
+}
+
+// runDiff compares the objects of two schemas, which may be on different
+// servers. It exits with status 2 when they differ, so it can gate deploys.
+func runDiff(ctx context.Context) int {
+	if flag.NArg() != 2 {
+		flag.Usage()
+		return 1
+	}
+	conn, err := loadConnection()
+	if err != nil {
+		fatal(err)
+	}
+	*outputFormat, err = validateOutputFormat(*outputFormat)
+	if err != nil {
+		fatal(err)
+	}
+	filter, err := parseFilter()
+	if err != nil {
+		fatal(err)
+	}
+
+	sourceHost, sourceSchema, err := parseEndpoint(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	targetHost, targetSchema, err := parseEndpoint(flag.Arg(1))
+	if err != nil {
+		fatal(err)
+	}
+	from, to := sourceHost+"/"+sourceSchema, targetHost+"/"+targetSchema
+
+	sourceObjects, err := loadSide(ctx, conn, sourceHost, sourceSchema, filter)
+	if err != nil {
+		fatalf("%s: %v", from, err)
+	}
+	targetObjects, err := loadSide(ctx, conn, targetHost, targetSchema, filter)
+	if err != nil {
+		fatalf("%s: %v", to, err)
+	}
+
+	diffs := schemadiff.Compare(sourceObjects, targetObjects, schemadiff.Options{
+		IgnoreDefiner:    *ignoreDefiner,
+		IgnoreWhitespace: *ignoreWhitespace,
+		SourceLabel:      from,
+		TargetLabel:      to,
+	})
+	if isStructuredOutput() {
+		if err := writeRecords(os.Stdout, *outputFormat, diffColumns, diffRows(from, to, diffs)); err != nil {
+			fatal(err)
+		}
+	} else {
+		printDiff(from, to, diffs, []string{schemadiff.Missing, schemadiff.Extra, schemadiff.Changed, schemadiff.DefinerChanged})
+	}
+
+	if len(diffs) > 0 {
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ChaosHour/go-pvt/pkg/connect"
+	"github.com/ChaosHour/go-pvt/pkg/ddl"
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+	"github.com/fatih/color"
+)
+
+// exporter writes the definition of every object it visits below dir
+type exporter struct {
+	dir string
+
+	mu   sync.Mutex
+	rows map[string][][]string // per host, one per file written, in exportColumns order
+}
+
+// visit writes the objects of one schema. It is called concurrently for
+// different hosts.
+func (e *exporter) visit(ctx context.Context, db *sql.DB, host, schema string, objects []inventory.Object) error {
+	for _, object := range objects {
+		def, err := ddl.ShowCreateOfType(ctx, db, schema, object.Name, object.Type)
+		if err != nil {
+			return fmt.Errorf("%s %s.%s: %v", object.Type, schema, object.Name, err)
+		}
+		// Views come back on a single line, which makes for unreadable diffs
+		if *pretty && def.Type == "VIEW" {
+			formatted, err := sqlparse.Format(def.CreateStatement, sqlparse.FormatOptions{KeywordCase: *keywordCase})
+			if err != nil {
+				return fmt.Errorf("VIEW %s.%s: %v", schema, def.Name, err)
+			}
+			def.CreateStatement = formatted
+		}
+		path := ddl.ExportPath(e.dir, host, def)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(ddl.ExportScript(def)), 0644); err != nil {
+			return err
+		}
+		logger.Debug("exported object", "host", host, "schema", schema, "name", def.Name, "type", def.Type, "path", path)
+
+		e.mu.Lock()
+		e.rows[host] = append(e.rows[host], []string{host, schema, def.Name, def.Type, path})
+		e.mu.Unlock()
+	}
+	return nil
+}
+
+// handleExport writes the definitions of the selected objects of every host
+// to dir/<host>/<schema>/<type>/<name>.sql. It returns the exit code.
+func handleExport(ctx context.Context, conn connect.Config, hosts []string, dir string, filter inventory.Filter) int {
+	e := &exporter{dir: dir, rows: make(map[string][][]string)}
+	results := inventory.ScanHosts(ctx, conn, hosts, inventory.ScanOptions{
+		Database:      *database,
+		AllDatabases:  *allDatabases,
+		IncludeSystem: *includeSystem,
+		Filter:        filter,
+		Workers:       *workers,
+		Connected:     printConnected,
+		Logger:        logger,
+		Visit:         e.visit,
+	})
+
+	exitCode := 0
+	var rows [][]string
+	for _, result := range results {
+		if result.Err != nil {
+			logger.Error("export failed", "host", result.Host, "error", result.Err)
+			exitCode = 1
+		}
+		rows = append(rows, e.rows[result.Host]...)
+	}
+
+	if isStructuredOutput() {
+		if err := writeRecords(os.Stdout, *outputFormat, exportColumns, rows); err != nil {
+			fatal(err)
+		}
+		return exitCode
+	}
+	fmt.Fprintf(statusOutput(), "%s: %d files to %s\n", color.GreenString("Exported"), len(rows), dir)
+	return exitCode
+}
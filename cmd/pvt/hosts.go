@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// parseHosts expands the -s value into a list of hosts. It accepts a single
+// host, a comma-separated list, or @file with one host per line.
+func parseHosts(spec string) ([]string, error) {
+	var raw []string
+	if strings.HasPrefix(spec, "@") {
+		file, err := os.Open(strings.TrimPrefix(spec, "@"))
+		if err != nil {
+			return nil, fmt.Errorf("error reading hosts file: %v", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			raw = append(raw, strings.Split(line, ",")...)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading hosts file: %v", err)
+		}
+	} else {
+		raw = strings.Split(spec, ",")
+	}
+
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, host := range raw {
+		host = strings.TrimSpace(host)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		hosts = append(hosts, host)
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no hosts given")
+	}
+	return hosts, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// logger writes diagnostics to stderr, at the level -v and -q select
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// setupLogging applies -v and -q once the flags are parsed
+func setupLogging() {
+	level := slog.LevelInfo
+	switch {
+	case *quiet:
+		level = slog.LevelError
+	case *verbose:
+		level = slog.LevelDebug
+	}
+	logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+}
+
+// fatal logs err and exits
+func fatal(err error) {
+	logger.Error(err.Error())
+	os.Exit(1)
+}
+
+// fatalf logs a formatted error and exits
+func fatalf(format string, args ...interface{}) {
+	fatal(fmt.Errorf(format, args...))
+}
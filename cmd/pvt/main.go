@@ -3,209 +3,101 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
+	"github.com/ChaosHour/go-pvt/pkg/connect"
+	"github.com/ChaosHour/go-pvt/pkg/ddl"
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
 	"github.com/fatih/color"
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/olekukonko/tablewriter"
 )
 
 // Define flags
 var (
-	source     = flag.String("s", "", "Source Host")
+	source     = flag.String("s", "", "Source Host (comma-separated list or @file for several hosts)")
 	database   = flag.String("d", "", "Database Name")
 	show       = flag.Bool("show", false, "Show Databases") // if the -show flag is set, show the databases and exit. Do not try to run the queries.
 	showCreate = flag.String("show-create", "", "Show CREATE statement for specified object name")
+	showAll    = flag.Bool("show-all", false, "Show every object with the -show-create name when several share it")
 	algorithm  = flag.String("algo", "", "Set algorithm for view (MERGE, TEMPTABLE)")
-	execute    = flag.Bool("true", false, "Execute the ALTER VIEW statement when -algo is specified")
+	execute    = flag.Bool("true", false, "Execute the ALTER VIEW statement when -algo is specified, or the -rewrite-definer plan")
+
+	verbose     = flag.Bool("v", false, "Log debug messages, such as every schema scanned")
+	quiet       = flag.Bool("q", false, "Only log errors, and hide status messages such as \"Connected to\"")
+	pretty      = flag.Bool("pretty", false, "Lay out the query of a view one clause per line with -show-create and -export")
+	keywordCase = flag.String("keyword-case", sqlparse.KeywordUpper, "Case of SQL keywords with -pretty: upper, lower or preserve")
+
+	currentAlgorithm = flag.String("current-algo", "", "Only select views currently using these algorithms, e.g. TEMPTABLE,UNDEFINED")
+	algoOut          = flag.String("algo-out", "", "Write the bulk ALTER VIEW statements to this file instead of stdout")
+
+	allDatabases  = flag.Bool("all-databases", false, "Scan every database on the server")
+	includeSystem = flag.Bool("include-system", false, "Include mysql, sys, information_schema and performance_schema with -all-databases")
+	workers       = flag.Int("workers", 4, "Number of hosts scanned concurrently")
+
+	rewriteDefiner = flag.String("rewrite-definer", "", "Move all objects from one definer to another, e.g. olduser@%=svc@% (applied with -true)")
+	orphans        = flag.Bool("orphans", false, "Report definers that do not exist in mysql.user, or are locked or expired")
+	typeFilter     = flag.String("type", "", "Only select objects of these types, e.g. PROCEDURE,FUNCTION. With -show-create, the type of the object to show")
+	namePattern    = flag.String("name", "", "Only select objects whose name matches these globs, e.g. rpt_*,tmp_?, or a /regex/")
+	excludePattern = flag.String("exclude", "", "Leave out objects whose name matches these globs or /regex/")
+	definerFilter  = flag.String("definer", "", "Only select objects owned by this definer, e.g. app@%")
+	tables         = flag.Bool("tables", false, "Also list tables, with their engine, size and collation under -detail")
+	detail         = flag.Bool("detail", false, "Add routine signatures and characteristics, trigger timing and event schedules to the inventory")
+	outputFormat   = flag.String("o", "table", "Output format (table, json, ndjson, csv, tsv, yaml, markdown)")
+
+	exportDir = flag.String("export", "", "Write the CREATE statement of every selected object to DIR/<host>/<schema>/<type>/<name>.sql")
+
+	ignoreDefiner    = flag.Bool("ignore-definer", false, "With diff and drift, do not compare definers")
+	ignoreWhitespace = flag.Bool("ignore-whitespace", false, "With diff and drift, treat definitions that only differ in whitespace as equal")
+
+	dependents = flag.Bool("dependents", false, "With deps, show the objects that use the selected ones: what breaks if they are dropped")
+	order      = flag.Bool("order", false, "With deps, list the selected objects in the order they can be created in")
+	dot        = flag.Bool("dot", false, "With deps, write the graph in Graphviz DOT format")
+
+	defaultsFile      = flag.String("defaults-file", "", "Only read MySQL options from the given file")
+	defaultsExtraFile = flag.String("defaults-extra-file", "", "Read this option file after the global option files")
+	defaultsSuffix    = flag.String("defaults-group-suffix", "", "Also read option groups with this suffix, e.g. [client_prod]")
+	loginPath         = flag.String("login-path", "client", "Read options from this login path in ~/.mylogin.cnf")
+
+	port           = flag.Int("P", 0, "Port number (default 3306)")
+	socket         = flag.String("S", "", "Unix socket file")
+	sslMode        = flag.String("ssl-mode", "", "TLS mode (DISABLED, PREFERRED, REQUIRED, VERIFY_CA, VERIFY_IDENTITY)")
+	sslCA          = flag.String("ssl-ca", "", "CA certificate file for TLS")
+	sslCert        = flag.String("ssl-cert", "", "Client certificate file for TLS")
+	sslKey         = flag.String("ssl-key", "", "Client key file for TLS")
+	connectTimeout = flag.Duration("connect-timeout", 0, "Connection timeout, e.g. 10s")
+	readTimeout    = flag.Duration("read-timeout", 0, "I/O read timeout, e.g. 30s")
+	charset        = flag.String("charset", "", "Connection character set")
 )
 
-// read the ~/.my.cnf file to get the database credentials
-func readMyCnf() error {
-	file, err := os.ReadFile(os.Getenv("HOME") + "/.my.cnf")
-	if err != nil {
-		return err
-	}
-	lines := strings.Split(string(file), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "user") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				os.Setenv("MYSQL_USER", strings.TrimSpace(parts[1]))
-			}
-		}
-		if strings.HasPrefix(line, "password") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				os.Setenv("MYSQL_PASSWORD", strings.TrimSpace(parts[1]))
-			}
-		}
-	}
-	return nil
-}
-
 // Connect to the database
-func connectToDatabase(source, database string) (*sql.DB, error) {
-	// Use information_schema if no specific database is provided
-	dbName := database
-	if dbName == "" {
-		dbName = "information_schema"
-	}
-
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", os.Getenv("MYSQL_USER"), os.Getenv("MYSQL_PASSWORD"), source, "3306", dbName))
-	if err != nil {
-		return nil, err
-	}
-	err = db.Ping()
+func connectToDatabase(ctx context.Context, conn connect.Config, source, database string) (*sql.DB, error) {
+	db, err := connect.Open(ctx, conn, source, database)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get the hostname of the connected MySQL server
-	var hostname string
-	err = db.QueryRow("SELECT @@hostname").Scan(&hostname)
+	hostname, err := connect.Hostname(ctx, db)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	// Print the result
-	fmt.Printf("Connected to %s (%s): %s\n", source, hostname, color.GreenString("✔"))
-	fmt.Println()
-
+	printConnected(source, hostname)
 	return db, nil
 }
 
-// Get the list of databases
-func getDatabases(db *sql.DB) ([]string, error) {
-	var databases []string
-	rows, err := db.Query("SELECT schema_name FROM information_schema.schemata")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	for rows.Next() {
-		var database string
-		err := rows.Scan(&database)
-		if err != nil {
-			return nil, err
-		}
-		databases = append(databases, database)
-	}
-	return databases, nil
-}
-
-// Get a list of all procedures, functions, and views in the specified database
-func getObjects(db *sql.DB, database string) ([][]string, error) {
-	var objects [][]string
-	query := `
-        SELECT ROUTINE_NAME, ROUTINE_TYPE, DEFINER FROM INFORMATION_SCHEMA.ROUTINES WHERE ROUTINE_SCHEMA = ?
-        UNION ALL
-        SELECT TABLE_NAME, 'VIEW', DEFINER FROM INFORMATION_SCHEMA.VIEWS WHERE TABLE_SCHEMA = ?
-        UNION ALL
-        SELECT TRIGGER_NAME, 'TRIGGER', DEFINER FROM INFORMATION_SCHEMA.TRIGGERS WHERE TRIGGER_SCHEMA = ?
-        UNION ALL
-        SELECT EVENT_NAME, 'EVENT', DEFINER FROM INFORMATION_SCHEMA.EVENTS WHERE EVENT_SCHEMA = ?
-    `
-	rows, err := db.Query(query, database, database, database, database)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	for rows.Next() {
-		var object, objectType, definer string
-		err := rows.Scan(&object, &objectType, &definer)
-		if err != nil {
-			return nil, err
-		}
-		objects = append(objects, []string{object, objectType, definer})
-	}
-	return objects, nil
-}
-
-// Get CREATE statement for a specific object
-func getCreateStatement(db *sql.DB, database, objectName string) error {
-	// First, check if the object exists and determine its type
-	var objectType string
-	typeQuery := `
-		SELECT 'VIEW' as type FROM INFORMATION_SCHEMA.VIEWS 
-		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
-		UNION ALL
-		SELECT ROUTINE_TYPE as type FROM INFORMATION_SCHEMA.ROUTINES 
-		WHERE ROUTINE_SCHEMA = ? AND ROUTINE_NAME = ?
-		UNION ALL
-		SELECT 'TRIGGER' as type FROM INFORMATION_SCHEMA.TRIGGERS 
-		WHERE TRIGGER_SCHEMA = ? AND TRIGGER_NAME = ?
-		UNION ALL
-		SELECT 'EVENT' as type FROM INFORMATION_SCHEMA.EVENTS 
-		WHERE EVENT_SCHEMA = ? AND EVENT_NAME = ?
-		LIMIT 1
-	`
-
-	err := db.QueryRow(typeQuery, database, objectName, database, objectName, database, objectName, database, objectName).Scan(&objectType)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("object '%s' not found in database '%s'", objectName, database)
-		}
-		return fmt.Errorf("error determining object type: %v", err)
-	}
-
-	// Now that we know the object type, we can get its create statement
-	var query string
-	switch objectType {
-	case "VIEW":
-		// For views, we need a different approach
-		var viewName, createStatement, characterSetClient, collationConnection string
-		query = fmt.Sprintf("SHOW CREATE VIEW `%s`.`%s`", database, objectName)
-		err := db.QueryRow(query).Scan(&viewName, &createStatement, &characterSetClient, &collationConnection)
-		if err != nil {
-			return fmt.Errorf("error getting CREATE VIEW statement: %v", err)
-		}
-		printCreateStatement(viewName, "VIEW", createStatement)
-		return nil
-	case "PROCEDURE", "FUNCTION":
-		query = fmt.Sprintf("SHOW CREATE %s `%s`.`%s`", objectType, database, objectName)
-		var name, sqlMode, createStatement, charset, collation, dbCollation string
-		err := db.QueryRow(query).Scan(&name, &sqlMode, &createStatement, &charset, &collation, &dbCollation)
-		if err != nil {
-			return fmt.Errorf("error getting CREATE %s statement: %v", objectType, err)
-		}
-		printCreateStatement(name, objectType, createStatement)
-		return nil
-	case "TRIGGER":
-		query = fmt.Sprintf("SHOW CREATE TRIGGER `%s`.`%s`", database, objectName)
-		var triggerName, sqlMode, createStatement, charset, collation, dbCollation string
-		err := db.QueryRow(query).Scan(&triggerName, &sqlMode, &createStatement, &charset, &collation, &dbCollation)
-		if err != nil {
-			return fmt.Errorf("error getting CREATE TRIGGER statement: %v", err)
-		}
-		printCreateStatement(triggerName, "TRIGGER", createStatement)
-		return nil
-	case "TABLE":
-		query = fmt.Sprintf("SHOW CREATE TABLE `%s`.`%s`", database, objectName)
-		var tableName, createStatement string
-		err := db.QueryRow(query).Scan(&tableName, &createStatement)
-		if err != nil {
-			return fmt.Errorf("error getting CREATE TABLE statement: %v", err)
-		}
-		printCreateStatement(tableName, "TABLE", createStatement)
-		return nil
-	case "EVENT":
-		query = fmt.Sprintf("SHOW CREATE EVENT `%s`.`%s`", database, objectName)
-		var eventName, createStatement string
-		err := db.QueryRow(query).Scan(&eventName, &createStatement)
-		if err != nil {
-			return fmt.Errorf("error getting CREATE EVENT statement: %v", err)
-		}
-		printCreateStatement(eventName, "EVENT", createStatement)
-		return nil
-	default:
-		return fmt.Errorf("unknown object type '%s' for '%s'", objectType, objectName)
-	}
+// Print the result of a successful connection
+func printConnected(source, hostname string) {
+	fmt.Fprintf(statusOutput(), "Connected to %s (%s): %s\n", source, hostname, color.GreenString("✔"))
+	fmt.Fprintln(statusOutput())
 }
 
 // Print CREATE statement vertically
@@ -219,19 +111,47 @@ func printCreateStatement(name, objType, createStatement string) {
 }
 
 // Print the objects
-func printResults(objects [][]string) {
+func printResults(objects []inventory.Object) error {
+	if isStructuredOutput() {
+		if *detail {
+			return writeRecords(os.Stdout, *outputFormat, append(inventoryColumns, detailColumns...), detailRows(objects))
+		}
+		return writeRecords(os.Stdout, *outputFormat, inventoryColumns, objectRows(objects))
+	}
+	if *detail {
+		printDetails(objects)
+		return nil
+	}
+
 	// Print the total number of objects
 	fmt.Printf("%s: %d\n", color.YellowString("Total"), len(objects))
 	fmt.Println()
 
 	// Print the objects in a MySQL-like table with colors
 	fmt.Println(color.New(color.FgGreen).Sprint("Objects:"))
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Name", "Type", "Definer"})
-	table.SetHeaderColor(
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor})
+	table := newTable([]string{"Host", "Schema", "Name", "Type", "Definer"})
+	table.AppendBulk(objectRows(objects))
+
+	table.Render()
+	return nil
+}
+
+// newTable returns a borderless MySQL-like table writing to stdout
+func newTable(header []string) *tablewriter.Table {
+	return newTableWriter(os.Stdout, header)
+}
+
+// newTableWriter returns a borderless MySQL-like table writing to w
+func newTableWriter(w io.Writer, header []string) *tablewriter.Table {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	if !color.NoColor {
+		colors := make([]tablewriter.Colors, len(header))
+		for i := range colors {
+			colors[i] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor}
+		}
+		table.SetHeaderColor(colors...)
+	}
 	table.SetAutoWrapText(false)
 	table.SetAutoFormatHeaders(true)
 	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
@@ -243,74 +163,19 @@ func printResults(objects [][]string) {
 	table.SetTablePadding(" ")
 	table.SetNoWhiteSpace(false)
 	table.SetBorder(false)
-
-	for _, object := range objects {
-		table.Append(object)
-	}
-
-	table.Render()
-}
-
-// Generate ALTER VIEW statement with specified algorithm
-func generateAlterViewStatement(db *sql.DB, database, viewName, algorithm string) (string, error) {
-	// Get the current view definition
-	var viewDef, createStmt, characterSetClient, collationConnection string
-	query := fmt.Sprintf("SHOW CREATE VIEW `%s`.`%s`", database, viewName)
-
-	err := db.QueryRow(query).Scan(&viewDef, &createStmt, &characterSetClient, &collationConnection)
-	if err != nil {
-		return "", fmt.Errorf("error getting view definition: %v", err)
-	}
-
-	// Extract the current definer and security settings
-	definerStart := strings.Index(createStmt, "DEFINER=")
-	definerEnd := strings.Index(createStmt[definerStart:], " SQL SECURITY")
-	if definerStart == -1 || definerEnd == -1 {
-		return "", fmt.Errorf("could not parse view definition")
-	}
-
-	definer := createStmt[definerStart : definerStart+definerEnd]
-
-	// Extract SQL SECURITY setting
-	securityStart := strings.Index(createStmt, "SQL SECURITY")
-	securityEnd := strings.Index(createStmt[securityStart:], " VIEW")
-	if securityStart == -1 || securityEnd == -1 {
-		return "", fmt.Errorf("could not parse SQL SECURITY setting")
-	}
-
-	security := createStmt[securityStart : securityStart+securityEnd]
-
-	// Extract the view definition (after AS)
-	asStart := strings.Index(createStmt, " AS ")
-	if asStart == -1 {
-		return "", fmt.Errorf("could not find view definition")
-	}
-
-	viewDefinition := createStmt[asStart+4:]
-
-	// Build the ALTER VIEW statement with semicolon at the end
-	alterStmt := fmt.Sprintf("ALTER \n    ALGORITHM = %s\n    %s\n    %s\n    VIEW `%s`.`%s` AS %s;",
-		algorithm, definer, security, database, viewName, viewDefinition)
-
-	return alterStmt, nil
-}
-
-// Execute ALTER VIEW statement
-func executeAlterViewStatement(db *sql.DB, alterStmt string) error {
-	_, err := db.Exec(alterStmt)
-	return err
+	return table
 }
 
 // Handle view algorithm settings
-func handleViewAlgorithm(db *sql.DB, database, viewName, algorithm string, execute bool) error {
+func handleViewAlgorithm(ctx context.Context, db *sql.DB, database, viewName, algorithm string, execute bool) error {
 	// Validate algorithm
-	algorithm = strings.ToUpper(algorithm)
-	if algorithm != "MERGE" && algorithm != "TEMPTABLE" && algorithm != "UNDEFINED" {
-		return fmt.Errorf("invalid algorithm: %s. Must be MERGE, TEMPTABLE, or UNDEFINED", algorithm)
+	algorithm, err := ddl.ValidateAlgorithm(algorithm)
+	if err != nil {
+		return err
 	}
 
 	// Generate the ALTER VIEW statement
-	alterStmt, err := generateAlterViewStatement(db, database, viewName, algorithm)
+	alterStmt, err := ddl.AlterViewAlgorithm(ctx, db, database, viewName, algorithm)
 	if err != nil {
 		return err
 	}
@@ -321,22 +186,119 @@ func handleViewAlgorithm(db *sql.DB, database, viewName, algorithm string, execu
 	fmt.Println(alterStmt)
 	fmt.Println(strings.Repeat("-", 80))
 
-	// If execute flag is set, execute the ALTER VIEW statement
+	// MySQL accepts MERGE for any view, but only stores it for views it can merge
+	if algorithm == "MERGE" {
+		if view, err := sqlparse.ParseCreateView(alterStmt); err == nil {
+			if blockers := view.MergeBlockers(); len(blockers) > 0 {
+				fmt.Printf("%s: MySQL cannot merge %s because of %s, so MySQL stores ALGORITHM=UNDEFINED\n",
+					color.YellowString("Warning"), viewName, strings.Join(blockers, ", "))
+			}
+		}
+	}
+
+	// If execute flag is set, execute the ALTER VIEW statement and check what it did
 	if execute {
 		fmt.Printf("Executing ALTER VIEW statement... ")
-		err := executeAlterViewStatement(db, alterStmt)
+		result, err := ddl.ApplyViewAlgorithm(ctx, db, database, viewName, algorithm, alterStmt)
 		if err != nil {
 			fmt.Printf("%s\n", color.RedString("Failed"))
 			return fmt.Errorf("error executing ALTER VIEW statement: %v", err)
 		}
-		fmt.Printf("%s\n", color.GreenString("Success"))
+		if !result.Honoured() {
+			fmt.Printf("%s\n", color.YellowString("Not applied"))
+		} else {
+			fmt.Printf("%s\n", color.GreenString("Success"))
+		}
+		for _, warning := range result.Warnings {
+			fmt.Printf("%s: %s\n", color.YellowString("Warning"), warning)
+		}
+		if !result.Honoured() {
+			return fmt.Errorf("view %s uses ALGORITHM=%s, not %s", viewName, result.Effective, algorithm)
+		}
 	}
 
 	return nil
 }
 
+// loadConnection reads the MySQL option files and combines them with the
+// connection flags. -s falls back to the host from the option files.
+func loadConnection() (connect.Config, error) {
+	// Read the MySQL option files to get the database credentials
+	opts, err := connect.ReadOptionFiles(connect.OptionFiles{
+		DefaultsFile: *defaultsFile,
+		ExtraFile:    *defaultsExtraFile,
+		GroupSuffix:  *defaultsSuffix,
+		LoginPath:    *loginPath,
+	})
+	if err != nil {
+		return connect.Config{}, err
+	}
+
+	// Fall back to the host from the option files when -s is not given
+	if *source == "" {
+		*source = opts.Get("host")
+	}
+	if *source == "" && *socket != "" {
+		*source = "localhost"
+	}
+
+	return connect.NewConfig(opts, connect.Config{
+		Port:           *port,
+		Socket:         *socket,
+		SSLMode:        *sslMode,
+		SSLCA:          *sslCA,
+		SSLCert:        *sslCert,
+		SSLKey:         *sslKey,
+		ConnectTimeout: *connectTimeout,
+		ReadTimeout:    *readTimeout,
+		Charset:        *charset,
+	})
+}
+
+// parseFilter builds the object filter from -type, -name, -exclude, -definer and -tables
+func parseFilter() (inventory.Filter, error) {
+	filter, err := inventory.ParseFilter(*typeFilter, *namePattern, *excludePattern, *definerFilter)
+	if err != nil {
+		return filter, err
+	}
+	if *tables {
+		filter = filter.WithType("TABLE")
+	}
+	return filter, nil
+}
+
+// Subcommands, which take the same flags as go-pvt itself after their name
+var commands = map[string]func(ctx context.Context) int{
+	"diff":     runDiff,
+	"snapshot": runSnapshot,
+	"drift":    runDrift,
+	"deps":     runDeps,
+}
+
+func init() {
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage: go-pvt [flags]\n")
+		fmt.Fprintf(out, "       go-pvt diff [flags] [HOST/]SCHEMA [HOST/]SCHEMA\n")
+		fmt.Fprintf(out, "       go-pvt snapshot [flags] [HOST/]SCHEMA FILE\n")
+		fmt.Fprintf(out, "       go-pvt drift [flags] FILE [[HOST/]SCHEMA]\n")
+		fmt.Fprintf(out, "       go-pvt deps [flags] [HOST/]SCHEMA[,SCHEMA...] [NAME]\n\n")
+		flag.PrintDefaults()
+	}
+}
+
 func main() {
+	ctx := context.Background()
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			flag.CommandLine.Parse(os.Args[2:])
+			setupLogging()
+			os.Exit(run(ctx))
+		}
+	}
+
 	flag.Parse()
+	setupLogging()
 
 	// if no flags are set, print the usage and exit
 	if len(os.Args) == 1 {
@@ -344,43 +306,148 @@ func main() {
 		os.Exit(0)
 	}
 
+	conn, err := loadConnection()
+	if err != nil {
+		fatal(err)
+	}
+
+	*outputFormat, err = validateOutputFormat(*outputFormat)
+	if err != nil {
+		fatal(err)
+	}
+
+	// Rewrite plans and single view changes are only printed in the human readable form.
+	// Bulk -algo can also export the views as JSON for view-formatter.
+	if (*rewriteDefiner != "" || (*algorithm != "" && *showCreate != "")) && isStructuredOutput() {
+		fatalf("-rewrite-definer and -show-create -algo can only be used with -o table")
+	}
+	if *algorithm != "" && *outputFormat != "table" && *outputFormat != "json" && *outputFormat != "ndjson" {
+		fatalf("-algo can only be used with -o table, json or ndjson")
+	}
+
 	// make sure that source and at least one of database or show is set
-	if *source == "" || (*database == "" && !*show && *showCreate == "") {
+	if *source == "" || (*database == "" && !*allDatabases && !*show && *showCreate == "" && *rewriteDefiner == "" && *algorithm == "") {
 		flag.Usage()
 		os.Exit(1)
 	}
 
+	filter, err := parseFilter()
+	if err != nil {
+		fatal(err)
+	}
+
+	hosts, err := parseHosts(*source)
+	if err != nil {
+		fatal(err)
+	}
+	logger.Debug("parsed hosts", "hosts", hosts)
+
+	// -show, -show-create, -algo and -rewrite-definer work against a single server
+	if len(hosts) > 1 && (*show || *showCreate != "" || *rewriteDefiner != "" || *algorithm != "") {
+		fatalf("-show, -show-create, -algo and -rewrite-definer accept a single host")
+	}
+
+	if *exportDir != "" && (*show || *showCreate != "" || *rewriteDefiner != "" || *algorithm != "") {
+		fatalf("-export cannot be combined with -show, -show-create, -algo or -rewrite-definer")
+	}
+
+	// make sure database is provided when rewriting definers or altering views in bulk
+	if (*rewriteDefiner != "" || *algorithm != "") && *database == "" {
+		flag.Usage()
+		fatalf("-d (database) flag is required when using -rewrite-definer or -algo")
+	}
+
 	// make sure database is provided when using show-create
 	if *showCreate != "" && *database == "" {
-		fmt.Println("Error: -d (database) flag is required when using -show-create")
 		flag.Usage()
-		os.Exit(1)
+		fatalf("-d (database) flag is required when using -show-create")
 	}
 
-	// Read the ~/.my.cnf file to get the database credentials
-	err := readMyCnf()
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	// Without -show, -show-create, -algo or -rewrite-definer, collect the inventory of every host
+	if !*show && *showCreate == "" && *rewriteDefiner == "" && *algorithm == "" {
+		// -export writes the definitions of the same objects instead of listing them
+		if *exportDir != "" {
+			if *orphans || *detail {
+				fatalf("-export cannot be combined with -orphans or -detail")
+			}
+			os.Exit(handleExport(ctx, conn, hosts, *exportDir, filter))
+		}
+
+		results := inventory.ScanHosts(ctx, conn, hosts, inventory.ScanOptions{
+			Database:      *database,
+			AllDatabases:  *allDatabases,
+			IncludeSystem: *includeSystem,
+			Orphans:       *orphans,
+			Detail:        *detail,
+			Filter:        filter,
+			Workers:       *workers,
+			Connected:     printConnected,
+			Logger:        logger,
+		})
+
+		var objects []inventory.Object
+		var findings []inventory.Finding
+		exitCode := 0
+		for _, result := range results {
+			if result.Err != nil {
+				logger.Error("scan failed", "host", result.Host, "error", result.Err)
+				exitCode = 1
+				continue
+			}
+			for _, warning := range result.Warnings {
+				logger.Warn(warning, "host", result.Host)
+			}
+			objects = append(objects, result.Objects...)
+			findings = append(findings, result.Findings...)
+		}
+
+		// With -orphans only the problem definers are listed, and any orphan
+		// fails the run so it can gate deploys
+		if *orphans {
+			if err := printOrphans(findings); err != nil {
+				fatal(err)
+			}
+			for _, finding := range findings {
+				if finding.IsOrphan() && exitCode == 0 {
+					exitCode = 2
+				}
+			}
+			os.Exit(exitCode)
+		}
+
+		// Print the objects in a MySQL-like table with colors
+		if err := printResults(objects); err != nil {
+			fatal(err)
+		}
+		os.Exit(exitCode)
 	}
 
 	// Connect to the database
-	db, err := connectToDatabase(*source, *database)
+	db, err := connectToDatabase(ctx, conn, hosts[0], *database)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fatal(err)
 	}
 	defer db.Close()
 
-	// Get the list of databases
-	databases, err := getDatabases(db)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-
 	// If the -show flag is set, show the databases and exit. Do not try to run the queries.
 	if *show {
+		// Get the list of databases
+		databases, err := inventory.Databases(ctx, db)
+		if err != nil {
+			fatal(err)
+		}
+
+		if isStructuredOutput() {
+			var rows [][]string
+			for _, database := range databases {
+				rows = append(rows, []string{hosts[0], database})
+			}
+			if err := writeRecords(os.Stdout, *outputFormat, databaseColumns, rows); err != nil {
+				fatal(err)
+			}
+			os.Exit(0)
+		}
+
 		fmt.Println(color.GreenString("Databases:"))
 		for _, database := range databases {
 			fmt.Println(database)
@@ -388,49 +455,28 @@ func main() {
 		os.Exit(0)
 	}
 
-	// If the -show-create flag is set, show CREATE statement and exit
-	if *showCreate != "" {
-		// First show the CREATE statement
-		err := getCreateStatement(db, *database, *showCreate)
+	// If the -rewrite-definer flag is set, print the plan, apply it with -true and exit
+	if *rewriteDefiner != "" {
+		err := handleDefinerRewrite(ctx, db, *database, *rewriteDefiner, *execute)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
 		}
+		os.Exit(0)
+	}
 
-		// If algorithm is specified and it's a view, handle algorithm change
-		if *algorithm != "" {
-			// Check if the object is a view
-			var count int
-			err := db.QueryRow("SELECT COUNT(*) FROM information_schema.views WHERE table_schema = ? AND table_name = ?",
-				*database, *showCreate).Scan(&count)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
-
-			if count > 0 {
-				// It's a view, handle algorithm change
-				err := handleViewAlgorithm(db, *database, *showCreate, *algorithm, *execute)
-				if err != nil {
-					fmt.Println(err)
-					os.Exit(1)
-				}
-			} else {
-				fmt.Printf("Error: -algo flag can only be used with views. %s is not a view.\n", *showCreate)
-				os.Exit(1)
-			}
+	// If -algo is set without -show-create, change the algorithm of every selected view
+	if *algorithm != "" && *showCreate == "" {
+		err := handleBulkViewAlgorithm(ctx, db, hosts[0], *database, *algorithm, filter, *execute)
+		if err != nil {
+			fatal(err)
 		}
-
 		os.Exit(0)
 	}
 
-	// Get a list of all procedures, functions, and views in the specified database
-	objects, err := getObjects(db, *database)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	// If the -show-create flag is set, show CREATE statement and exit
+	if *showCreate != "" {
+		if err := handleShowCreate(ctx, db, hosts[0], *database, *showCreate, filter); err != nil {
+			fatal(err)
+		}
 	}
-
-	// Print the objects in a MySQL-like table with colors
-	printResults(objects)
 }
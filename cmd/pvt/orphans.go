@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/fatih/color"
+)
+
+var orphanColumns = []string{"host", "schema", "name", "type", "definer", "status", "detail"}
+
+// Print the definer findings
+func printOrphans(findings []inventory.Finding) error {
+	if isStructuredOutput() {
+		return writeRecords(os.Stdout, *outputFormat, orphanColumns, findingRows(findings))
+	}
+
+	orphans := 0
+	for _, finding := range findings {
+		if finding.IsOrphan() {
+			orphans++
+		}
+	}
+	fmt.Printf("%s: %d\n", color.YellowString("Orphaned definers"), orphans)
+	fmt.Printf("%s: %d\n", color.YellowString("Warnings"), len(findings)-orphans)
+	fmt.Println()
+	if len(findings) == 0 {
+		return nil
+	}
+
+	table := newTable([]string{"Host", "Schema", "Name", "Type", "Definer", "Status", "Detail"})
+	for i, row := range findingRows(findings) {
+		if findings[i].IsOrphan() {
+			row[5] = color.RedString(row[5])
+		} else {
+			row[5] = color.YellowString(row[5])
+		}
+		table.Append(row)
+	}
+	table.Render()
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/ddl"
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+)
+
+// Output formats accepted by -o
+var outputFormats = []string{"table", "json", "ndjson", "csv", "tsv", "yaml", "markdown"}
+
+// Record keys of the structured outputs. These are part of the documented
+// interface, so only ever add keys, never rename or reorder them.
+var (
+	inventoryColumns = []string{"host", "schema", "name", "type", "definer"}
+	databaseColumns  = []string{"host", "database"}
+	createColumns    = []string{"host", "schema", "name", "type", "sql_mode", "character_set_client", "collation_connection", "create_statement"}
+	viewColumns      = []string{"host", "schema", "name", "definer", "algorithm", "new_algorithm", "character_set_client", "collation_connection", "create_statement", "alter_statement", "merge_blockers"}
+	exportColumns    = []string{"host", "schema", "name", "type", "path"}
+	diffColumns      = []string{"source", "target", "type", "name", "status", "source_definer", "target_definer", "diff"}
+	depsColumns      = []string{"host", "schema", "name", "type", "uses_schema", "uses_name", "uses_type", "uses_status"}
+	orderColumns     = []string{"host", "position", "schema", "name", "type"}
+
+	// detailColumns follow inventoryColumns with -detail
+	detailColumns = []string{
+		"parameters", "returns", "security_type", "is_deterministic", "sql_data_access", "sql_mode", "created", "last_altered", "comment",
+		"table", "timing", "event_manipulation", "action_order", "referenced_tables",
+		"status", "schedule", "interval", "starts", "ends", "last_executed", "on_completion",
+		"engine", "row_format", "table_rows", "data_length", "index_length", "auto_increment", "table_collation",
+	}
+)
+
+// validateOutputFormat normalizes and checks the -o value
+func validateOutputFormat(format string) (string, error) {
+	format = strings.ToLower(format)
+	if format == "md" {
+		format = "markdown"
+	}
+	for _, f := range outputFormats {
+		if f == format {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("invalid output format: %s. Must be one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// isStructuredOutput reports whether the output is meant for other programs.
+// Status messages go to stderr in that case so stdout stays parseable.
+func isStructuredOutput() bool {
+	return *outputFormat != "table"
+}
+
+// statusOutput is where informational messages such as "Connected to" go.
+// They are dropped with -q.
+func statusOutput() io.Writer {
+	if *quiet {
+		return io.Discard
+	}
+	if isStructuredOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// writeRecords renders rows in one of the structured formats. Every row must
+// have one value per column; columns double as JSON/YAML keys and CSV headers.
+func writeRecords(w io.Writer, format string, columns []string, rows [][]string) error {
+	switch format {
+	case "json":
+		if len(rows) == 0 {
+			_, err := fmt.Fprintln(w, "[]")
+			return err
+		}
+		if _, err := fmt.Fprintln(w, "["); err != nil {
+			return err
+		}
+		for i, row := range rows {
+			sep := ","
+			if i == len(rows)-1 {
+				sep = ""
+			}
+			if _, err := fmt.Fprintf(w, "  %s%s\n", jsonObject(columns, row), sep); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintln(w, "]")
+		return err
+	case "ndjson":
+		for _, row := range rows {
+			if _, err := fmt.Fprintln(w, jsonObject(columns, row)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv", "tsv":
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	case "yaml":
+		if len(rows) == 0 {
+			_, err := fmt.Fprintln(w, "[]")
+			return err
+		}
+		for _, row := range rows {
+			for i, col := range columns {
+				prefix := "  "
+				if i == 0 {
+					prefix = "- "
+				}
+				// JSON strings are valid YAML double-quoted scalars
+				if _, err := fmt.Fprintf(w, "%s%s: %s\n", prefix, col, jsonString(row[i])); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case "markdown":
+		escaped := make([]string, len(columns))
+		for i, col := range columns {
+			escaped[i] = markdownCell(col)
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(escaped, " | "))
+		fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(columns)))
+		for _, row := range rows {
+			cells := make([]string, len(row))
+			for i, value := range row {
+				cells[i] = markdownCell(value)
+			}
+			if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | ")); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// jsonObject encodes a row as a JSON object with keys in column order
+func jsonObject(columns []string, row []string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, col := range columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(jsonString(col))
+		b.WriteByte(':')
+		b.WriteString(jsonString(row[i]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func jsonString(s string) string {
+	out, _ := json.Marshal(s)
+	return string(out)
+}
+
+// markdownCell escapes pipes and flattens newlines so a value fits in one cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// objectRows converts inventory objects to rows in inventoryColumns order
+func objectRows(objects []inventory.Object) [][]string {
+	rows := make([][]string, len(objects))
+	for i, o := range objects {
+		rows[i] = []string{o.Host, o.Schema, o.Name, o.Type, o.Definer}
+	}
+	return rows
+}
+
+// detailRows converts inventory objects to rows in inventoryColumns order
+// followed by detailColumns
+func detailRows(objects []inventory.Object) [][]string {
+	rows := objectRows(objects)
+	for i, o := range objects {
+		rows[i] = append(rows[i], o.Parameters, o.Returns, o.Security, o.Deterministic,
+			o.DataAccess, o.SQLMode, o.Created, o.LastAltered, o.Comment,
+			o.Table, o.Timing, o.Manipulation, o.ActionOrder, o.ReferencedTables,
+			o.Status, o.Schedule, o.Interval, o.Starts, o.Ends, o.LastExecuted, o.OnCompletion,
+			o.Engine, o.RowFormat, o.Rows, o.DataLength, o.IndexLength, o.AutoIncrement, o.Collation)
+	}
+	return rows
+}
+
+// findingRows converts definer findings to rows in orphanColumns order
+func findingRows(findings []inventory.Finding) [][]string {
+	rows := make([][]string, len(findings))
+	for i, f := range findings {
+		rows[i] = []string{f.Host, f.Schema, f.Name, f.Type, f.Definer, f.Status, f.Detail}
+	}
+	return rows
+}
+
+// viewRows converts selected views to rows in viewColumns order
+func viewRows(server, schema, algorithm string, views []ddl.ViewChange) [][]string {
+	rows := make([][]string, len(views))
+	for i, v := range views {
+		rows[i] = []string{server, schema, v.Name, v.Definer, v.Algorithm, algorithm,
+			v.CharacterSetClient, v.CollationConnection, v.CreateStatement, v.AlterStatement, strings.Join(v.MergeBlockers, ", ")}
+	}
+	return rows
+}
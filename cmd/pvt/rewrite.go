@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/ddl"
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/fatih/color"
+)
+
+// printRewritePlan prints the plan as a script the mysql client can replay
+func printRewritePlan(steps []ddl.RewriteStep, to string) {
+	fmt.Printf("%s:\n", color.GreenString("Definer Rewrite Plan"))
+	fmt.Println(strings.Repeat("-", 80))
+	for _, step := range steps {
+		fmt.Printf("-- %s %s: %s -> %s\n", step.Type, step.Name, step.Definer, to)
+		if step.Type == "TRIGGER" {
+			fmt.Println("-- Note: a recreated trigger moves to the end of its table's trigger order")
+		}
+		if step.Type == "PROCEDURE" || step.Type == "FUNCTION" {
+			fmt.Println("-- Note: routine-level grants are removed by DROP and must be granted again")
+		}
+		for _, stmt := range step.Setup {
+			fmt.Printf("%s;\n", stmt)
+		}
+		for _, stmt := range step.Statements {
+			// Compound bodies contain semicolons, so switch delimiter for them
+			if strings.HasPrefix(strings.ToUpper(stmt), "CREATE") {
+				fmt.Printf("DELIMITER ;;\n%s;;\nDELIMITER ;\n", stmt)
+				continue
+			}
+			fmt.Printf("%s;\n", stmt)
+		}
+		fmt.Println()
+	}
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%s: %d\n", color.YellowString("Objects to rewrite"), len(steps))
+}
+
+// Handle definer rewrites for every object in a database
+func handleDefinerRewrite(ctx context.Context, db *sql.DB, database, spec string, execute bool) error {
+	from, to, found := strings.Cut(spec, "=")
+	if !found {
+		return fmt.Errorf("invalid -rewrite-definer value %q, expected from=to", spec)
+	}
+	toUser, toHost, err := inventory.ParseDefiner(to)
+	if err != nil {
+		return err
+	}
+
+	steps, err := ddl.PlanDefinerRewrite(ctx, db, database, from, to)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		fmt.Printf("No objects in %s are owned by %s\n", database, from)
+		return nil
+	}
+
+	// Creating objects for a missing account only works with a warning, so say so up front
+	if accounts, err := inventory.LoadAccounts(ctx, db); err == nil {
+		if status, detail := accounts.CheckDefiner(toUser + "@" + toHost); status == inventory.DefinerMissing || status == inventory.DefinerHostMismatch {
+			fmt.Printf("%s: new definer %s@%s: %s\n", color.YellowString("Warning"), toUser, toHost, detail)
+		}
+	}
+
+	printRewritePlan(steps, ddl.QuoteDefiner(toUser, toHost))
+
+	// If execute flag is set, apply the plan
+	if !execute {
+		fmt.Println("Dry run only. Add -true to apply the plan.")
+		return nil
+	}
+
+	failed := 0
+	for _, step := range steps {
+		fmt.Printf("Rewriting %s %s... ", step.Type, step.Name)
+		if err := step.Execute(ctx, db); err != nil {
+			fmt.Printf("%s\n", color.RedString("Failed"))
+			fmt.Printf("  %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s\n", color.GreenString("Success"))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d objects could not be rewritten", failed, len(steps))
+	}
+	return nil
+}
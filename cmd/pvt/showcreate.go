@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/ddl"
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// selectTypes picks the types of the objects called name that -show-create
+// shows. found comes from ddl.ObjectTypes; -type narrows it, -algo only
+// applies to views, and otherwise several matches need -show-all.
+func selectTypes(database, name string, found []string, filter inventory.Filter) ([]string, error) {
+	// Without -type every type is a candidate, tables included
+	var types []string
+	for _, t := range found {
+		if len(filter.Types) == 0 || filter.HasType(t) {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("%w: no %s named '%s' in database '%s', only %s",
+			ddl.ErrNotFound, strings.Join(filter.Types, " or "), name, database, strings.Join(found, ", "))
+	}
+
+	if *algorithm != "" {
+		for _, t := range types {
+			if t == "VIEW" {
+				return []string{t}, nil
+			}
+		}
+		return nil, fmt.Errorf("-algo flag can only be used with views. %s is not a view.", name)
+	}
+
+	// Naming several types with -type asks for each of them. -tables also
+	// fills in filter.Types, but does not pick between the matches.
+	if len(types) > 1 && *typeFilter == "" && !*showAll {
+		err := &ddl.AmbiguousError{Schema: database, Name: name, Types: types}
+		return nil, fmt.Errorf("%v. Pick one with -type, or show them all with -show-all", err)
+	}
+	return types, nil
+}
+
+// Handle -show-create: print the CREATE statement of every selected object
+// called name, then change the algorithm of the view with -algo
+func handleShowCreate(ctx context.Context, db *sql.DB, server, database, name string, filter inventory.Filter) error {
+	// A routine, trigger or event may share its name with any other object
+	found, err := ddl.ObjectTypes(ctx, db, database, name)
+	if err != nil {
+		return err
+	}
+	types, err := selectTypes(database, name, found, filter)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for i, t := range types {
+		def, err := ddl.ShowCreateOfType(ctx, db, database, name, t)
+		if err != nil {
+			return err
+		}
+		// Views come back from the server on a single line. Routines, triggers
+		// and events keep the author's layout, so they are left as they are.
+		if *pretty && def.Type == "VIEW" {
+			def.CreateStatement, err = sqlparse.Format(def.CreateStatement, sqlparse.FormatOptions{KeywordCase: *keywordCase})
+			if err != nil {
+				return err
+			}
+		}
+		if isStructuredOutput() {
+			rows = append(rows, []string{server, database, def.Name, def.Type, def.SQLMode,
+				def.CharacterSetClient, def.CollationConnection, def.CreateStatement})
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		printCreateStatement(def.Name, def.Type, def.CreateStatement)
+	}
+	if isStructuredOutput() {
+		if err := writeRecords(os.Stdout, *outputFormat, createColumns, rows); err != nil {
+			return err
+		}
+	}
+
+	// selectTypes only leaves the view when -algo is given
+	if *algorithm != "" {
+		return handleViewAlgorithm(ctx, db, database, name, *algorithm, *execute)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/ChaosHour/go-pvt/pkg/schemadiff"
+	"github.com/fatih/color"
+)
+
+// saveSnapshot writes s to path, or stdout for "-". The file is replaced
+// in one step, so a failed run leaves the previous baseline in place.
+func saveSnapshot(path string, s schemadiff.Snapshot) error {
+	if path == "-" {
+		return schemadiff.WriteSnapshot(os.Stdout, s)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := schemadiff.WriteSnapshot(tmp, s); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadSnapshot reads the snapshot at path, or stdin for "-"
+func loadSnapshot(path string) (schemadiff.Snapshot, error) {
+	if path == "-" {
+		return schemadiff.ReadSnapshot(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return schemadiff.Snapshot{}, err
+	}
+	defer f.Close()
+	s, err := schemadiff.ReadSnapshot(f)
+	if err != nil {
+		return s, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+// runSnapshot saves the objects of a schema, with their definitions and
+// checksums, to a JSON file for drift to compare against
+func runSnapshot(ctx context.Context) int {
+	if flag.NArg() != 2 {
+		flag.Usage()
+		return 1
+	}
+	conn, err := loadConnection()
+	if err != nil {
+		fatal(err)
+	}
+	filter, err := parseFilter()
+	if err != nil {
+		fatal(err)
+	}
+	host, schema, err := parseEndpoint(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	path := flag.Arg(1)
+
+	objects, err := loadSide(ctx, conn, host, schema, filter)
+	if err != nil {
+		fatalf("%s/%s: %v", host, schema, err)
+	}
+	if err := saveSnapshot(path, schemadiff.NewSnapshot(host, schema, objects, time.Now())); err != nil {
+		fatal(err)
+	}
+	if path != "-" {
+		fmt.Fprintf(statusOutput(), "%s: %d objects of %s/%s to %s\n", color.GreenString("Saved"), len(objects), host, schema, path)
+	}
+	return 0
+}
+
+// runDrift compares a live schema with a snapshot, by default the schema the
+// snapshot was taken of. It exits with status 2 on drift, so it can alert
+// from cron.
+// filterDrift keeps the objects of the snapshot and of the live schema that
+// pass filter on at least one side
+func filterDrift(filter inventory.Filter, baseline []schemadiff.SnapshotObject, live []schemadiff.Object) ([]schemadiff.SnapshotObject, []schemadiff.Object) {
+	key := func(o schemadiff.Object) string { return o.Type + "\x00" + o.Name }
+	match := func(o schemadiff.Object) bool {
+		return filter.Match(inventory.Object{Name: o.Name, Type: o.Type, Definer: o.Definer})
+	}
+	keep := make(map[string]bool)
+	for _, o := range baseline {
+		if match(o.Object) {
+			keep[key(o.Object)] = true
+		}
+	}
+	for _, o := range live {
+		if match(o) {
+			keep[key(o)] = true
+		}
+	}
+
+	var selected []schemadiff.SnapshotObject
+	for _, o := range baseline {
+		if keep[key(o.Object)] {
+			selected = append(selected, o)
+		}
+	}
+	var current []schemadiff.Object
+	for _, o := range live {
+		if keep[key(o)] {
+			current = append(current, o)
+		}
+	}
+	return selected, current
+}
+
+func runDrift(ctx context.Context) int {
+	if flag.NArg() != 1 && flag.NArg() != 2 {
+		flag.Usage()
+		return 1
+	}
+	conn, err := loadConnection()
+	if err != nil {
+		fatal(err)
+	}
+	*outputFormat, err = validateOutputFormat(*outputFormat)
+	if err != nil {
+		fatal(err)
+	}
+	filter, err := parseFilter()
+	if err != nil {
+		fatal(err)
+	}
+
+	path := flag.Arg(0)
+	baseline, err := loadSnapshot(path)
+	if err != nil {
+		fatal(err)
+	}
+	host, schema := baseline.Host, baseline.Schema
+	if flag.NArg() == 2 {
+		host, schema, err = parseEndpoint(flag.Arg(1))
+		if err != nil {
+			fatal(err)
+		}
+	}
+	live := host + "/" + schema
+
+	// The filters narrow the snapshot the same way they narrow the live
+	// schema. An object whose definer changed only matches -definer on one
+	// side, so it is kept on both when either side matches.
+	nameFilter := filter
+	nameFilter.Definer = ""
+	objects, err := loadSide(ctx, conn, host, schema, nameFilter)
+	if err != nil {
+		fatalf("%s: %v", live, err)
+	}
+	baseline.Objects, objects = filterDrift(filter, baseline.Objects, objects)
+	diffs := schemadiff.Drift(baseline, objects, schemadiff.Options{
+		IgnoreDefiner:    *ignoreDefiner,
+		IgnoreWhitespace: *ignoreWhitespace,
+		SourceLabel:      path,
+		TargetLabel:      live,
+	})
+	if isStructuredOutput() {
+		if err := writeRecords(os.Stdout, *outputFormat, diffColumns, diffRows(path, live, diffs)); err != nil {
+			fatal(err)
+		}
+	} else {
+		from := fmt.Sprintf("%s (%s/%s at %s)", path, baseline.Host, baseline.Schema, baseline.TakenAt.Format(time.RFC3339))
+		printDiff(from, live, diffs, []string{schemadiff.Added, schemadiff.Removed, schemadiff.Modified, schemadiff.DefinerChanged})
+	}
+
+	if len(diffs) > 0 {
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,257 @@
+package connect
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Config holds everything needed to reach a server, merged from command
+// line flags and option files.
+type Config struct {
+	User           string
+	Password       string
+	Port           int
+	Socket         string
+	ForceSocket    bool // use Socket even for non-local hosts
+	SSLMode        string
+	SSLCA          string
+	SSLCert        string
+	SSLKey         string
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	Charset        string
+}
+
+// NewConfig merges explicit settings, typically from flags, with the option
+// file values. Non-zero fields of explicit always win. A socket given
+// explicitly is used for every host.
+func NewConfig(opts Options, explicit Config) (Config, error) {
+	c := Config{
+		User:           firstNonEmpty(explicit.User, opts.Get("user")),
+		Password:       firstNonEmpty(explicit.Password, opts.Get("password")),
+		Socket:         firstNonEmpty(explicit.Socket, opts.Get("socket")),
+		ForceSocket:    explicit.ForceSocket || explicit.Socket != "",
+		SSLMode:        strings.ToUpper(firstNonEmpty(explicit.SSLMode, opts.Get("ssl-mode"))),
+		SSLCA:          firstNonEmpty(explicit.SSLCA, opts.Get("ssl-ca")),
+		SSLCert:        firstNonEmpty(explicit.SSLCert, opts.Get("ssl-cert")),
+		SSLKey:         firstNonEmpty(explicit.SSLKey, opts.Get("ssl-key")),
+		ConnectTimeout: explicit.ConnectTimeout,
+		ReadTimeout:    explicit.ReadTimeout,
+		Charset:        firstNonEmpty(explicit.Charset, opts.Get("default-character-set")),
+		Port:           explicit.Port,
+	}
+
+	if c.Port == 0 && opts.Get("port") != "" {
+		p, err := strconv.Atoi(opts.Get("port"))
+		if err != nil {
+			return c, fmt.Errorf("invalid port %q in option file", opts.Get("port"))
+		}
+		c.Port = p
+	}
+	if c.Port == 0 {
+		c.Port = 3306
+	}
+
+	// Option files express timeouts in whole seconds
+	if c.ConnectTimeout == 0 && opts.Get("connect-timeout") != "" {
+		secs, err := strconv.Atoi(opts.Get("connect-timeout"))
+		if err != nil {
+			return c, fmt.Errorf("invalid connect-timeout %q in option file", opts.Get("connect-timeout"))
+		}
+		c.ConnectTimeout = time.Duration(secs) * time.Second
+	}
+	if c.ReadTimeout == 0 && opts.Get("read-timeout") != "" {
+		secs, err := strconv.Atoi(opts.Get("read-timeout"))
+		if err != nil {
+			return c, fmt.Errorf("invalid read-timeout %q in option file", opts.Get("read-timeout"))
+		}
+		c.ReadTimeout = time.Duration(secs) * time.Second
+	}
+
+	// Like the mysql client, a CA implies certificate verification unless told otherwise
+	if c.SSLMode == "" && c.SSLCA != "" {
+		c.SSLMode = "VERIFY_CA"
+	}
+	if c.SSLMode == "" && opts.Has("skip-ssl") {
+		c.SSLMode = "DISABLED"
+	}
+	switch c.SSLMode {
+	case "", "DISABLED", "PREFERRED", "REQUIRED", "VERIFY_CA", "VERIFY_IDENTITY":
+	default:
+		return c, fmt.Errorf("invalid ssl-mode: %s. Must be DISABLED, PREFERRED, REQUIRED, VERIFY_CA or VERIFY_IDENTITY", c.SSLMode)
+	}
+
+	return c, nil
+}
+
+// MySQLConfig builds a driver config for the given host and database.
+// The host may carry its own port as host:port, which overrides the default.
+func (c Config) MySQLConfig(source, database string) (*mysql.Config, error) {
+	cfg := mysql.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.DBName = database
+	cfg.Timeout = c.ConnectTimeout
+	cfg.ReadTimeout = c.ReadTimeout
+	if c.Charset != "" {
+		cfg.Params = map[string]string{"charset": c.Charset}
+	}
+
+	// A socket is used when asked for explicitly, or when the host is local
+	// and the option files name one, mirroring the mysql client.
+	port := c.Port
+	if port == 0 {
+		port = 3306
+	}
+	host, portStr := source, strconv.Itoa(port)
+	if h, p, err := net.SplitHostPort(source); err == nil {
+		host, portStr = h, p
+	}
+	if c.Socket != "" && (c.ForceSocket || host == "" || host == "localhost") {
+		cfg.Net = "unix"
+		cfg.Addr = c.Socket
+	} else {
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(host, portStr)
+	}
+
+	tlsConfig, err := c.TLSConfig(host)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tlsConfig != nil:
+		cfg.TLS = tlsConfig
+		cfg.AllowFallbackToPlaintext = c.SSLMode == "" || c.SSLMode == "PREFERRED"
+	case c.SSLMode == "PREFERRED" || (c.SSLMode == "" && cfg.Net == "tcp"):
+		cfg.TLSConfig = "preferred"
+	}
+
+	return cfg, nil
+}
+
+// TLSConfig translates the ssl-mode family of options into a tls.Config.
+// It returns nil when TLS is disabled or merely preferred.
+func (c Config) TLSConfig(host string) (*tls.Config, error) {
+	switch c.SSLMode {
+	case "DISABLED":
+		return nil, nil
+	case "", "PREFERRED":
+		// Only worth a custom config when a client certificate must be presented
+		if c.SSLCert == "" {
+			return nil, nil
+		}
+	}
+
+	cfg := &tls.Config{}
+
+	if c.SSLCert != "" || c.SSLKey != "" {
+		if c.SSLCert == "" || c.SSLKey == "" {
+			return nil, fmt.Errorf("both ssl-cert and ssl-key are required for a client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(c.SSLCert, c.SSLKey)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	var roots *x509.CertPool
+	if c.SSLCA != "" {
+		pem, err := os.ReadFile(c.SSLCA)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ssl-ca: %v", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.SSLCA)
+		}
+	}
+
+	switch c.SSLMode {
+	case "VERIFY_IDENTITY":
+		cfg.RootCAs = roots
+		cfg.ServerName = host
+	case "VERIFY_CA":
+		// Verify the chain but not the host name, as the mysql client does
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyChain(rawCerts, roots)
+		}
+	default:
+		cfg.InsecureSkipVerify = true
+	}
+
+	return cfg, nil
+}
+
+// Open connects to a server and checks the connection. An empty database
+// connects to information_schema.
+func Open(ctx context.Context, c Config, source, database string) (*sql.DB, error) {
+	if database == "" {
+		database = "information_schema"
+	}
+
+	cfg, err := c.MySQLConfig(source, database)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db := sql.OpenDB(connector)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Hostname returns the hostname the connected server reports
+func Hostname(ctx context.Context, db *sql.DB) (string, error) {
+	var hostname string
+	err := db.QueryRowContext(ctx, "SELECT @@hostname").Scan(&hostname)
+	return hostname, err
+}
+
+// verifyChain checks the server certificate chain against roots without a host name check
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("server presented no certificate")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+	return err
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
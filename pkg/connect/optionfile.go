@@ -0,0 +1,375 @@
+// Package connect reads MySQL option files and opens connections the way the
+// mysql command line client does.
+package connect
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Option file groups read by the mysql client, in the order MySQL documents them
+var optionGroups = []string{"client", "mysql"}
+
+// Options holds the merged key/value pairs read from the option files.
+// Keys are normalized to lower case with dashes, the way mysqld does it.
+type Options map[string]string
+
+// Get returns the value for key, or an empty string if it was never set
+func (o Options) Get(key string) string {
+	return o[normalizeOptionKey(key)]
+}
+
+// Has reports whether key was set in any option file
+func (o Options) Has(key string) bool {
+	_, ok := o[normalizeOptionKey(key)]
+	return ok
+}
+
+// optionFileReader merges option files in precedence order
+type optionFileReader struct {
+	groups  map[string]bool
+	options Options
+	seen    map[string]bool
+}
+
+func newOptionFileReader(groups []string, suffix string) *optionFileReader {
+	r := &optionFileReader{
+		groups:  make(map[string]bool),
+		options: make(Options),
+		seen:    make(map[string]bool),
+	}
+	for _, g := range groups {
+		r.groups[strings.ToLower(g)] = true
+		if suffix != "" {
+			r.groups[strings.ToLower(g+suffix)] = true
+		}
+	}
+	return r
+}
+
+// defaultOptionFiles returns the standard option file search order.
+// Later files take precedence over earlier ones.
+func defaultOptionFiles(extraFile string) []string {
+	files := []string{"/etc/my.cnf", "/etc/mysql/my.cnf"}
+	if mysqlHome := os.Getenv("MYSQL_HOME"); mysqlHome != "" {
+		files = append(files, filepath.Join(mysqlHome, "my.cnf"))
+	}
+	if extraFile != "" {
+		files = append(files, extraFile)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".my.cnf"))
+	}
+	return files
+}
+
+// OptionFiles selects which option files are read, mirroring the mysql
+// client's --defaults-file, --defaults-extra-file, --defaults-group-suffix
+// and --login-path options.
+type OptionFiles struct {
+	DefaultsFile string
+	ExtraFile    string
+	GroupSuffix  string
+	LoginPath    string
+}
+
+// ReadOptionFiles reads the MySQL option files and returns the merged client options.
+// If DefaultsFile is set, only that file is read (it must exist). The login
+// path file ~/.mylogin.cnf is always read last, like the mysql client does.
+func ReadOptionFiles(files OptionFiles) (Options, error) {
+	r := newOptionFileReader(optionGroups, files.GroupSuffix)
+	loginPath := files.LoginPath
+
+	if files.DefaultsFile != "" {
+		if err := r.readFile(files.DefaultsFile, true); err != nil {
+			return nil, err
+		}
+	} else {
+		if files.ExtraFile != "" {
+			if _, err := os.Stat(files.ExtraFile); err != nil {
+				return nil, fmt.Errorf("could not open defaults-extra-file: %v", err)
+			}
+		}
+		for _, file := range defaultOptionFiles(files.ExtraFile) {
+			if err := r.readFile(file, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Login paths from ~/.mylogin.cnf override the plain option files
+	if loginPath != "" {
+		r.groups[strings.ToLower(loginPath)] = true
+	}
+	loginFile := os.Getenv("MYSQL_TEST_LOGIN_FILE")
+	if loginFile == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			loginFile = filepath.Join(home, ".mylogin.cnf")
+		}
+	}
+	if loginFile != "" {
+		if err := r.readLoginFile(loginFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.options, nil
+}
+
+// readFile parses a single option file. Missing files are skipped unless required.
+func (r *optionFileReader) readFile(path string, required bool) error {
+	abs, err := filepath.Abs(path)
+	if err == nil {
+		path = abs
+	}
+	if r.seen[path] {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil
+		}
+		return fmt.Errorf("could not read option file %s: %v", path, err)
+	}
+	r.seen[path] = true
+
+	return r.parse(path, bytes.NewReader(data))
+}
+
+// parse reads option file content, following !include and !includedir directives
+func (r *optionFileReader) parse(path string, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	group := ""
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		// Include directives are processed regardless of the current group
+		if strings.HasPrefix(line, "!includedir") {
+			dir := strings.TrimSpace(strings.TrimPrefix(line, "!includedir"))
+			if err := r.readDir(r.resolve(path, dir)); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "!include") {
+			file := strings.TrimSpace(strings.TrimPrefix(line, "!include"))
+			if err := r.readFile(r.resolve(path, file), false); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end == -1 {
+				return fmt.Errorf("%s:%d: invalid group header %q", path, lineNo, line)
+			}
+			group = strings.ToLower(strings.TrimSpace(line[1:end]))
+			continue
+		}
+
+		if !r.groups[group] {
+			continue
+		}
+
+		key, value, err := parseOptionLine(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", path, lineNo, err)
+		}
+		r.options[key] = value
+	}
+	return scanner.Err()
+}
+
+// readDir reads every *.cnf file in dir in lexical order
+func (r *optionFileReader) readDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("could not read option directory %s: %v", dir, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".cnf") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := r.readFile(filepath.Join(dir, name), false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve makes include paths relative to the including file
+func (r *optionFileReader) resolve(from, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(from), path)
+}
+
+// readLoginFile decrypts and parses a mysql_config_editor login file
+func (r *optionFileReader) readLoginFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("could not read login file %s: %v", path, err)
+	}
+	plain, err := decryptLoginFile(data)
+	if err != nil {
+		return fmt.Errorf("could not decrypt login file %s: %v", path, err)
+	}
+	return r.parse(path, bytes.NewReader(plain))
+}
+
+// decryptLoginFile decodes the obfuscated .mylogin.cnf format: 4 unused bytes,
+// a 20 byte key, then length-prefixed AES-128-ECB encrypted lines.
+func decryptLoginFile(data []byte) ([]byte, error) {
+	const keyOffset, keyLen = 4, 20
+	if len(data) < keyOffset+keyLen {
+		return nil, fmt.Errorf("file too short")
+	}
+
+	var key [aes.BlockSize]byte
+	for i, b := range data[keyOffset : keyOffset+keyLen] {
+		key[i%aes.BlockSize] ^= b
+	}
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	rest := data[keyOffset+keyLen:]
+	for len(rest) >= 4 {
+		size := int(binary.LittleEndian.Uint32(rest[:4]))
+		rest = rest[4:]
+		if size > len(rest) || size%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("corrupt cipher block")
+		}
+		chunk := make([]byte, size)
+		for i := 0; i < size; i += aes.BlockSize {
+			block.Decrypt(chunk[i:i+aes.BlockSize], rest[i:i+aes.BlockSize])
+		}
+		rest = rest[size:]
+
+		// Strip PKCS#7 padding
+		if pad := int(chunk[len(chunk)-1]); pad > 0 && pad <= aes.BlockSize && pad <= len(chunk) {
+			chunk = chunk[:len(chunk)-pad]
+		}
+		out.Write(chunk)
+	}
+	return out.Bytes(), nil
+}
+
+// parseOptionLine splits a "key = value" line, handling quotes, escapes and
+// trailing comments. As in the mysql client, a # outside quotes starts a
+// comment wherever it is, so password=abc#x sets the password abc; values
+// containing # must be quoted.
+func parseOptionLine(line string) (string, string, error) {
+	key, value, found := strings.Cut(removeOptionComment(line), "=")
+	key = normalizeOptionKey(key)
+	if key == "" {
+		return "", "", fmt.Errorf("invalid option %q", line)
+	}
+	if !found {
+		return key, "", nil
+	}
+
+	value = strings.TrimSpace(value)
+	if value != "" && (value[0] == '"' || value[0] == '\'') {
+		if len(value) < 2 || value[len(value)-1] != value[0] {
+			return "", "", fmt.Errorf("unterminated quote in option %q", key)
+		}
+		value = value[1 : len(value)-1]
+	}
+	return key, unescapeOptionValue(value), nil
+}
+
+// removeOptionComment cuts line at the first # outside quotes. Inside quotes
+// a backslash escapes the next character.
+func removeOptionComment(line string) string {
+	var quote byte
+	escaped := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0 && c == '\\':
+			escaped = true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unescapeOptionValue expands the escape sequences MySQL allows in option values
+func unescapeOptionValue(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' || i == len(value)-1 {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 's':
+			b.WriteByte(' ')
+		case '\\', '"', '\'':
+			b.WriteByte(value[i])
+		default:
+			// Unknown escapes keep the backslash, e.g. Windows paths
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// normalizeOptionKey lower-cases a key, maps underscores to dashes and drops the loose- prefix
+func normalizeOptionKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	key = strings.ReplaceAll(key, "_", "-")
+	return strings.TrimPrefix(key, "loose-")
+}
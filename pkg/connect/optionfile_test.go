@@ -0,0 +1,207 @@
+package connect
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseOptionLine(t *testing.T) {
+	tests := []struct {
+		line       string
+		key, value string
+	}{
+		{"host=db1", "host", "db1"},
+		{"  Default_Character_Set = utf8mb4  ", "default-character-set", "utf8mb4"},
+		{"loose-ssl_mode=REQUIRED", "ssl-mode", "REQUIRED"},
+		{"skip-ssl", "skip-ssl", ""},
+		{"password=", "password", ""},
+		{"password = abc # old one", "password", "abc"},
+		{"password=abc#x", "password", "abc"},
+		{`password="abc#x"`, "password", "abc#x"},
+		{`password='it''s' # note`, "password", "it''s"},
+		{`password="a\"b#c" # note`, "password", `a"b#c`},
+		{`password = "a b" `, "password", "a b"},
+		{`socket=C:\mysql\mysql.sock`, "socket", `C:\mysql\mysql.sock`},
+		{`password=tab\there\sspace\\`, "password", "tab\there space\\"},
+	}
+	for _, tt := range tests {
+		key, value, err := parseOptionLine(tt.line)
+		if err != nil {
+			t.Errorf("parseOptionLine(%q) error: %v", tt.line, err)
+			continue
+		}
+		if key != tt.key || value != tt.value {
+			t.Errorf("parseOptionLine(%q) = %q, %q, want %q, %q", tt.line, key, value, tt.key, tt.value)
+		}
+	}
+
+	for _, bad := range []string{"= value", `password="abc`, `password='abc" `} {
+		if _, _, err := parseOptionLine(bad); err == nil {
+			t.Errorf("parseOptionLine(%q) accepted an invalid line", bad)
+		}
+	}
+}
+
+func TestUnescapeOptionValue(t *testing.T) {
+	tests := []struct{ value, want string }{
+		{"plain", "plain"},
+		{`a\nb\rc\bd`, "a\nb\rc\bd"},
+		{`a\sb\tc`, "a b\tc"},
+		{`\\server\\share`, `\server\share`},
+		{`\"quoted\'`, `"quoted'`},
+		{`C:\Program Files\MySQL`, `C:\Program Files\MySQL`},
+		{`trailing\`, `trailing\`},
+	}
+	for _, tt := range tests {
+		if got := unescapeOptionValue(tt.value); got != tt.want {
+			t.Errorf("unescapeOptionValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// writeFiles creates files below dir from a map of relative path to content
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadOptionFilesIncludes(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MYSQL_TEST_LOGIN_FILE", filepath.Join(dir, "missing.mylogin.cnf"))
+	writeFiles(t, dir, map[string]string{
+		"my.cnf": "[client]\nuser=base\nhost=db1\n" +
+			"!include extra/more.cnf\n" +
+			"!includedir conf.d\n" +
+			"[mysqld]\nport=3307\n",
+		// Relative to the including file, and including my.cnf back
+		"extra/more.cnf": "[client]\nport=3310\n!include ../my.cnf\n",
+		// Read in lexical order; files without .cnf are skipped
+		"conf.d/20-user.cnf": "[mysql]\nuser=app\n",
+		"conf.d/10-user.cnf": "[client]\nuser=early\nsocket=/tmp/a.sock\n",
+		"conf.d/notes.txt":   "[client]\nuser=ignored\n",
+		// Includes each other
+		"conf.d/30-loop.cnf": "!include " + filepath.Join(dir, "conf.d", "40-loop.cnf") + "\n[client]\nconnect-timeout=5\n",
+		"conf.d/40-loop.cnf": "!include 30-loop.cnf\n[client]\nread-timeout=7\n",
+	})
+
+	opts, err := ReadOptionFiles(OptionFiles{DefaultsFile: filepath.Join(dir, "my.cnf")})
+	if err != nil {
+		t.Fatalf("ReadOptionFiles() error: %v", err)
+	}
+	want := map[string]string{
+		"user":            "app",
+		"host":            "db1",
+		"port":            "3310",
+		"socket":          "/tmp/a.sock",
+		"connect-timeout": "5",
+		"read-timeout":    "7",
+	}
+	for key, value := range want {
+		if got := opts.Get(key); got != value {
+			t.Errorf("Get(%q) = %q, want %q", key, got, value)
+		}
+	}
+	if len(opts) != len(want) {
+		t.Errorf("ReadOptionFiles() = %v, want %v", opts, want)
+	}
+
+	if _, err := ReadOptionFiles(OptionFiles{DefaultsFile: filepath.Join(dir, "none.cnf")}); err == nil {
+		t.Error("ReadOptionFiles() accepted a missing defaults file")
+	}
+}
+
+// encryptLoginFile encrypts content the way mysql_config_editor writes
+// .mylogin.cnf: 4 unused bytes, a 20 byte key, then each line as a 4 byte
+// little-endian length and the line, PKCS#7 padded and AES-128-ECB encrypted
+// with the key folded to 16 bytes by XOR.
+func encryptLoginFile(t *testing.T, key [20]byte, lines []string) []byte {
+	t.Helper()
+	var aesKey [aes.BlockSize]byte
+	for i, b := range key {
+		aesKey[i%aes.BlockSize] ^= b
+	}
+	block, err := aes.NewCipher(aesKey[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.NewBuffer(make([]byte, 4))
+	out.Write(key[:])
+	for _, line := range lines {
+		plain := []byte(line + "\n")
+		pad := aes.BlockSize - len(plain)%aes.BlockSize
+		plain = append(plain, bytes.Repeat([]byte{byte(pad)}, pad)...)
+		cipher := make([]byte, len(plain))
+		for i := 0; i < len(plain); i += aes.BlockSize {
+			block.Encrypt(cipher[i:i+aes.BlockSize], plain[i:i+aes.BlockSize])
+		}
+		binary.Write(out, binary.LittleEndian, uint32(len(cipher)))
+		out.Write(cipher)
+	}
+	return out.Bytes()
+}
+
+// loginFileFixture holds [client], user = "root" and password = "s3cr3t#!"
+// under the key 0e1c071f...1e191600, each line encrypted by
+// openssl enc -aes-128-ecb rather than by encryptLoginFile
+const loginFileFixture = "000000000e1c071f0a17020f06051d13110c0b081e191600" +
+	"10000000d122e828303a5dcc166f34a1cfa3abab" +
+	"10000000c0ff3173905e2ccac2bf1ae7c1264474" +
+	"20000000441141c95ddfd704230a6f74c71295991239ae530233c44d4742b1a159142d6c"
+
+func TestDecryptLoginFile(t *testing.T) {
+	fixture, err := hex.DecodeString(loginFileFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := decryptLoginFile(fixture)
+	if err != nil {
+		t.Fatalf("decryptLoginFile() error: %v", err)
+	}
+	if want := "[client]\nuser = \"root\"\npassword = \"s3cr3t#!\"\n"; string(plain) != want {
+		t.Errorf("decryptLoginFile() = %q, want %q", plain, want)
+	}
+
+	var key [20]byte
+	copy(key[:], fixture[4:24])
+	if got := encryptLoginFile(t, key, []string{"[client]", `user = "root"`, `password = "s3cr3t#!"`}); !bytes.Equal(got, fixture) {
+		t.Fatalf("encryptLoginFile() = %x, want the fixture", got)
+	}
+	data := encryptLoginFile(t, key, []string{"[client]", `user = "root"`, `password = "s3cr3t#!"`,
+		"[backup]", `user = "backup"`, `host = "db2.example.com"`})
+
+	// The login path is read last and overrides the option files
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"my.cnf": "[client]\nuser=app\nhost=db1\npassword=old\n"})
+	loginFile := filepath.Join(dir, ".mylogin.cnf")
+	if err := os.WriteFile(loginFile, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MYSQL_TEST_LOGIN_FILE", loginFile)
+	opts, err := ReadOptionFiles(OptionFiles{DefaultsFile: filepath.Join(dir, "my.cnf"), LoginPath: "backup"})
+	if err != nil {
+		t.Fatalf("ReadOptionFiles() error: %v", err)
+	}
+	if opts.Get("user") != "backup" || opts.Get("host") != "db2.example.com" || opts.Get("password") != "s3cr3t#!" {
+		t.Errorf("ReadOptionFiles() with login path = %v", opts)
+	}
+
+	for _, bad := range [][]byte{data[:10], append(append([]byte(nil), data[:28]...), 0x07, 0, 0, 0)} {
+		if _, err := decryptLoginFile(bad); err == nil {
+			t.Errorf("decryptLoginFile(%x) accepted a corrupt file", bad)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Package ddl retrieves CREATE statements and generates the DDL go-pvt
+// applies, such as ALTER VIEW and definer rewrites.
+package ddl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Definition is the SHOW CREATE output of one object together with the
+// session settings it was created under. Views and tables have no sql_mode.
+type Definition struct {
+	Schema              string `json:"schema"`
+	Name                string `json:"name"`
+	Type                string `json:"type"`
+	CreateStatement     string `json:"create_statement"`
+	SQLMode             string `json:"sql_mode"`
+	CharacterSetClient  string `json:"character_set_client"`
+	CollationConnection string `json:"collation_connection"`
+}
+
+// ObjectTypes returns the types of every object in schema with the given
+// name. Tables and views share a namespace, but a routine, trigger or event
+// may have the name of any other object, so there can be several. Tables and
+// views come first, then procedures, functions, triggers and events.
+func ObjectTypes(ctx context.Context, db *sql.DB, schema, name string) ([]string, error) {
+	typeQuery := `
+		SELECT 1 AS priority, IF(TABLE_TYPE = 'VIEW', 'VIEW', 'TABLE') AS type FROM INFORMATION_SCHEMA.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND TABLE_TYPE IN ('BASE TABLE', 'VIEW')
+		UNION ALL
+		SELECT IF(ROUTINE_TYPE = 'PROCEDURE', 2, 3), ROUTINE_TYPE FROM INFORMATION_SCHEMA.ROUTINES
+		WHERE ROUTINE_SCHEMA = ? AND ROUTINE_NAME = ?
+		UNION ALL
+		SELECT 4, 'TRIGGER' FROM INFORMATION_SCHEMA.TRIGGERS
+		WHERE TRIGGER_SCHEMA = ? AND TRIGGER_NAME = ?
+		UNION ALL
+		SELECT 5, 'EVENT' FROM INFORMATION_SCHEMA.EVENTS
+		WHERE EVENT_SCHEMA = ? AND EVENT_NAME = ?
+		ORDER BY priority
+	`
+
+	rows, err := db.QueryContext(ctx, typeQuery, schema, name, schema, name, schema, name, schema, name)
+	if err != nil {
+		return nil, fmt.Errorf("error determining object type: %v", err)
+	}
+	defer rows.Close()
+	var types []string
+	for rows.Next() {
+		var priority int
+		var objectType string
+		if err := rows.Scan(&priority, &objectType); err != nil {
+			return nil, fmt.Errorf("error determining object type: %v", err)
+		}
+		types = append(types, objectType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error determining object type: %v", err)
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("%w: '%s' in database '%s'", ErrNotFound, name, schema)
+	}
+	return types, nil
+}
+
+// AmbiguousError is returned when several objects have the name asked for
+type AmbiguousError struct {
+	Schema string
+	Name   string
+	Types  []string // in the order ObjectTypes returns them
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("'%s' in database '%s' matches several objects: %s", e.Name, e.Schema, strings.Join(e.Types, ", "))
+}
+
+// ObjectType returns the type of the named object in schema, or an
+// *AmbiguousError when several objects share the name
+func ObjectType(ctx context.Context, db *sql.DB, schema, name string) (string, error) {
+	types, err := ObjectTypes(ctx, db, schema, name)
+	if err != nil {
+		return "", err
+	}
+	if len(types) > 1 {
+		return "", &AmbiguousError{Schema: schema, Name: name, Types: types}
+	}
+	return types[0], nil
+}
+
+// ShowCreate returns the CREATE statement of the named object, whatever its
+// type. It returns an *AmbiguousError when several objects share the name.
+func ShowCreate(ctx context.Context, db *sql.DB, schema, name string) (Definition, error) {
+	objectType, err := ObjectType(ctx, db, schema, name)
+	if err != nil {
+		return Definition{}, err
+	}
+	return ShowCreateOfType(ctx, db, schema, name, objectType)
+}
+
+// ShowCreateOfType returns the CREATE statement of an object whose type is already known
+func ShowCreateOfType(ctx context.Context, db *sql.DB, schema, name, objectType string) (Definition, error) {
+	def := Definition{Schema: schema, Type: objectType}
+	qualified := sqlparse.QuoteIdentifier(schema) + "." + sqlparse.QuoteIdentifier(name)
+	switch objectType {
+	case "VIEW":
+		err := db.QueryRowContext(ctx, "SHOW CREATE VIEW "+qualified).
+			Scan(&def.Name, &def.CreateStatement, &def.CharacterSetClient, &def.CollationConnection)
+		if err != nil {
+			return def, fmt.Errorf("error getting CREATE VIEW statement: %v", err)
+		}
+	case "PROCEDURE", "FUNCTION":
+		var dbCollation string
+		err := db.QueryRowContext(ctx, "SHOW CREATE "+objectType+" "+qualified).
+			Scan(&def.Name, &def.SQLMode, &def.CreateStatement, &def.CharacterSetClient, &def.CollationConnection, &dbCollation)
+		if err != nil {
+			return def, fmt.Errorf("error getting CREATE %s statement: %v", objectType, err)
+		}
+	case "TRIGGER":
+		var dbCollation string
+		var created sql.NullString // NULL for triggers created before MySQL 5.7.2
+		err := db.QueryRowContext(ctx, "SHOW CREATE TRIGGER "+qualified).
+			Scan(&def.Name, &def.SQLMode, &def.CreateStatement, &def.CharacterSetClient, &def.CollationConnection, &dbCollation, &created)
+		if err != nil {
+			return def, fmt.Errorf("error getting CREATE TRIGGER statement: %v", err)
+		}
+	case "TABLE":
+		err := db.QueryRowContext(ctx, "SHOW CREATE TABLE "+qualified).Scan(&def.Name, &def.CreateStatement)
+		if err != nil {
+			return def, fmt.Errorf("error getting CREATE TABLE statement: %v", err)
+		}
+	case "EVENT":
+		var timeZone, dbCollation string
+		err := db.QueryRowContext(ctx, "SHOW CREATE EVENT "+qualified).
+			Scan(&def.Name, &def.SQLMode, &timeZone, &def.CreateStatement, &def.CharacterSetClient, &def.CollationConnection, &dbCollation)
+		if err != nil {
+			return def, fmt.Errorf("error getting CREATE EVENT statement: %v", err)
+		}
+	default:
+		return def, fmt.Errorf("unknown object type '%s' for '%s'", objectType, name)
+	}
+	return def, nil
+}
+
+// QuoteString renders a SQL string literal
+func QuoteString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// QuoteDefiner renders user and host the way SHOW CREATE does
+func QuoteDefiner(user, host string) string {
+	return sqlparse.QuoteIdentifier(user) + "@" + sqlparse.QuoteIdentifier(host)
+}
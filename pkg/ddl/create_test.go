@@ -0,0 +1,75 @@
+package ddl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ChaosHour/go-pvt/pkg/internal/fakedb"
+)
+
+// Results of SHOW CREATE for one object of every type, as MySQL 8.0 returns them
+var showCreateResults = []fakedb.Result{
+	{
+		Match:   "SHOW CREATE TRIGGER",
+		Columns: []string{"Trigger", "sql_mode", "SQL Original Statement", "character_set_client", "collation_connection", "Database Collation", "Created"},
+		Rows: [][]interface{}{{"orders_ai", "STRICT_TRANS_TABLES",
+			"CREATE DEFINER=`app`@`%` TRIGGER `orders_ai` AFTER INSERT ON `orders` FOR EACH ROW INSERT INTO audit_log (id) VALUES (NEW.id)",
+			"utf8mb4", "utf8mb4_0900_ai_ci", "utf8mb4_0900_ai_ci", "2024-05-01 02:00:00.12"}},
+	},
+	{
+		Match:   "SHOW CREATE PROCEDURE",
+		Columns: []string{"Procedure", "sql_mode", "Create Procedure", "character_set_client", "collation_connection", "Database Collation"},
+		Rows: [][]interface{}{{"get_x", "STRICT_TRANS_TABLES", "CREATE DEFINER=`app`@`%` PROCEDURE `get_x`()\nSELECT 1",
+			"utf8mb4", "utf8mb4_0900_ai_ci", "utf8mb4_0900_ai_ci"}},
+	},
+	{
+		Match:   "SHOW CREATE VIEW",
+		Columns: []string{"View", "Create View", "character_set_client", "collation_connection"},
+		Rows: [][]interface{}{{"v", "CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `v` AS select 1 AS `x`",
+			"utf8mb4", "utf8mb4_0900_ai_ci"}},
+	},
+	{
+		Match:   "SHOW CREATE EVENT",
+		Columns: []string{"Event", "sql_mode", "time_zone", "Create Event", "character_set_client", "collation_connection", "Database Collation"},
+		Rows: [][]interface{}{{"purge", "", "SYSTEM", "CREATE DEFINER=`app`@`%` EVENT `purge` ON SCHEDULE EVERY 1 DAY DO DELETE FROM t",
+			"utf8mb4", "utf8mb4_0900_ai_ci", "utf8mb4_0900_ai_ci"}},
+	},
+	{
+		Match:   "SHOW CREATE TABLE",
+		Columns: []string{"Table", "Create Table"},
+		Rows:    [][]interface{}{{"orders", "CREATE TABLE `orders` (\n  `id` int NOT NULL\n) ENGINE=InnoDB"}},
+	},
+}
+
+func TestShowCreateOfType(t *testing.T) {
+	db, _ := fakedb.Open(t, showCreateResults...)
+	tests := []struct {
+		name, objectType, sqlMode, prefix string
+	}{
+		{"orders_ai", "TRIGGER", "STRICT_TRANS_TABLES", "CREATE DEFINER=`app`@`%` TRIGGER"},
+		{"get_x", "PROCEDURE", "STRICT_TRANS_TABLES", "CREATE DEFINER=`app`@`%` PROCEDURE"},
+		{"v", "VIEW", "", "CREATE ALGORITHM=UNDEFINED"},
+		{"purge", "EVENT", "", "CREATE DEFINER=`app`@`%` EVENT"},
+		{"orders", "TABLE", "", "CREATE TABLE"},
+	}
+	for _, tt := range tests {
+		def, err := ShowCreateOfType(context.Background(), db, "app", tt.name, tt.objectType)
+		if err != nil {
+			t.Errorf("ShowCreateOfType(%s %s) error: %v", tt.objectType, tt.name, err)
+			continue
+		}
+		if def.Name != tt.name || def.Type != tt.objectType || def.SQLMode != tt.sqlMode || len(def.CreateStatement) < len(tt.prefix) || def.CreateStatement[:len(tt.prefix)] != tt.prefix {
+			t.Errorf("ShowCreateOfType(%s %s) = %+v", tt.objectType, tt.name, def)
+		}
+	}
+
+	// Triggers created before MySQL 5.7.2 have no creation time
+	db, _ = fakedb.Open(t, fakedb.Result{
+		Match:   "SHOW CREATE TRIGGER",
+		Columns: showCreateResults[0].Columns,
+		Rows:    [][]interface{}{{"old_trigger", "", "CREATE TRIGGER `old_trigger` BEFORE DELETE ON `t` FOR EACH ROW SET @x = 1", "latin1", "latin1_swedish_ci", "latin1_swedish_ci", nil}},
+	})
+	if _, err := ShowCreateOfType(context.Background(), db, "app", "old_trigger", "TRIGGER"); err != nil {
+		t.Errorf("ShowCreateOfType() of a trigger without Created: %v", err)
+	}
+}
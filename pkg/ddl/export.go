@@ -0,0 +1,89 @@
+package ddl
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// ExportPath returns the file an object is exported to:
+// dir/<host>/<schema>/<type>/<name>.sql. Characters that are not safe in
+// file names are percent-encoded, so every object gets a file of its own.
+func ExportPath(dir, host string, def Definition) string {
+	return filepath.Join(dir, exportFileName(host), exportFileName(def.Schema),
+		strings.ToLower(def.Type), exportFileName(def.Name)+".sql")
+}
+
+// exportFileName encodes name for use as a single path element
+func exportFileName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		safe := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '_' || c == '-' || c == '$' || (c == '.' && i > 0)
+		if safe {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// ExportScript renders def as a script the mysql client can replay into the
+// object's schema, e.g. mysql app < file. The session settings the object was
+// created under are set before the CREATE statement and restored after it.
+// Stored programs are wrapped in DELIMITER ;; since their bodies contain
+// semicolons. The script holds nothing that changes between runs, so exports
+// of an unchanged schema are identical.
+func ExportScript(def Definition) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- %s %s.%s\n", def.Type, sqlparse.QuoteIdentifier(def.Schema), sqlparse.QuoteIdentifier(def.Name))
+
+	var settings [][2]string
+	if def.SQLMode != "" || isStoredProgram(def.Type) {
+		settings = append(settings, [2]string{"sql_mode", def.SQLMode})
+	}
+	if def.CharacterSetClient != "" {
+		settings = append(settings, [2]string{"character_set_client", def.CharacterSetClient})
+	}
+	if def.CollationConnection != "" {
+		settings = append(settings, [2]string{"collation_connection", def.CollationConnection})
+	}
+	for _, s := range settings {
+		fmt.Fprintf(&b, "-- %s: %s\n", s[0], s[1])
+	}
+	b.WriteString("\n")
+
+	if len(settings) > 0 {
+		for _, s := range settings {
+			fmt.Fprintf(&b, "SET @saved_%s = @@SESSION.%s;\n", s[0], s[0])
+		}
+		for _, s := range settings {
+			fmt.Fprintf(&b, "SET SESSION %s = %s;\n", s[0], QuoteString(s[1]))
+		}
+		b.WriteString("\n")
+	}
+
+	create := strings.TrimRight(strings.TrimSpace(def.CreateStatement), ";")
+	if isStoredProgram(def.Type) {
+		fmt.Fprintf(&b, "DELIMITER ;;\n%s;;\nDELIMITER ;\n", create)
+	} else {
+		fmt.Fprintf(&b, "%s;\n", create)
+	}
+
+	if len(settings) > 0 {
+		b.WriteString("\n")
+		for _, s := range settings {
+			fmt.Fprintf(&b, "SET SESSION %s = @saved_%s;\n", s[0], s[0])
+		}
+	}
+	return b.String()
+}
+
+// isStoredProgram reports whether objects of type t have a body of statements
+func isStoredProgram(t string) bool {
+	return t == "PROCEDURE" || t == "FUNCTION" || t == "TRIGGER" || t == "EVENT"
+}
@@ -0,0 +1,75 @@
+package ddl
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExportScript(t *testing.T) {
+	tests := []struct {
+		name string
+		def  Definition
+		want string
+	}{
+		{
+			name: "procedure",
+			def: Definition{Schema: "app", Name: "p", Type: "PROCEDURE", SQLMode: "STRICT_TRANS_TABLES",
+				CharacterSetClient: "utf8mb4", CollationConnection: "utf8mb4_0900_ai_ci",
+				CreateStatement: "CREATE DEFINER=`app`@`%` PROCEDURE `p`()\nBEGIN\n  SELECT 1;\nEND"},
+			want: "-- PROCEDURE `app`.`p`\n" +
+				"-- sql_mode: STRICT_TRANS_TABLES\n-- character_set_client: utf8mb4\n-- collation_connection: utf8mb4_0900_ai_ci\n\n" +
+				"SET @saved_sql_mode = @@SESSION.sql_mode;\nSET @saved_character_set_client = @@SESSION.character_set_client;\nSET @saved_collation_connection = @@SESSION.collation_connection;\n" +
+				"SET SESSION sql_mode = 'STRICT_TRANS_TABLES';\nSET SESSION character_set_client = 'utf8mb4';\nSET SESSION collation_connection = 'utf8mb4_0900_ai_ci';\n\n" +
+				"DELIMITER ;;\nCREATE DEFINER=`app`@`%` PROCEDURE `p`()\nBEGIN\n  SELECT 1;\nEND;;\nDELIMITER ;\n\n" +
+				"SET SESSION sql_mode = @saved_sql_mode;\nSET SESSION character_set_client = @saved_character_set_client;\nSET SESSION collation_connection = @saved_collation_connection;\n",
+		},
+		{
+			name: "view without sql_mode",
+			def: Definition{Schema: "app", Name: "v", Type: "VIEW", CharacterSetClient: "utf8mb4", CollationConnection: "utf8mb4_general_ci",
+				CreateStatement: "CREATE VIEW `v` AS select 1 AS `x`"},
+			want: "-- VIEW `app`.`v`\n-- character_set_client: utf8mb4\n-- collation_connection: utf8mb4_general_ci\n\n" +
+				"SET @saved_character_set_client = @@SESSION.character_set_client;\nSET @saved_collation_connection = @@SESSION.collation_connection;\n" +
+				"SET SESSION character_set_client = 'utf8mb4';\nSET SESSION collation_connection = 'utf8mb4_general_ci';\n\n" +
+				"CREATE VIEW `v` AS select 1 AS `x`;\n\n" +
+				"SET SESSION character_set_client = @saved_character_set_client;\nSET SESSION collation_connection = @saved_collation_connection;\n",
+		},
+		{
+			name: "trigger",
+			def: Definition{Schema: "app", Name: "orders_ai", Type: "TRIGGER",
+				CreateStatement: "CREATE DEFINER=`app`@`%` TRIGGER `orders_ai` AFTER INSERT ON `orders` FOR EACH ROW BEGIN\n  INSERT INTO audit_log (id) VALUES (NEW.id);\nEND"},
+			want: "-- TRIGGER `app`.`orders_ai`\n-- sql_mode: \n\n" +
+				"SET @saved_sql_mode = @@SESSION.sql_mode;\nSET SESSION sql_mode = '';\n\n" +
+				"DELIMITER ;;\nCREATE DEFINER=`app`@`%` TRIGGER `orders_ai` AFTER INSERT ON `orders` FOR EACH ROW BEGIN\n  INSERT INTO audit_log (id) VALUES (NEW.id);\nEND;;\nDELIMITER ;\n\n" +
+				"SET SESSION sql_mode = @saved_sql_mode;\n",
+		},
+		{
+			name: "table",
+			def:  Definition{Schema: "app", Name: "t", Type: "TABLE", CreateStatement: "CREATE TABLE `t` (\n  `id` int\n)"},
+			want: "-- TABLE `app`.`t`\n\nCREATE TABLE `t` (\n  `id` int\n);\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExportScript(tt.def); got != tt.want {
+				t.Errorf("ExportScript() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportPath(t *testing.T) {
+	tests := []struct {
+		host string
+		def  Definition
+		want string
+	}{
+		{"primary", Definition{Schema: "app", Name: "my_proc", Type: "PROCEDURE"}, "out/primary/app/procedure/my_proc.sql"},
+		{"db1:3307", Definition{Schema: "app", Name: "a/b c", Type: "VIEW"}, "out/db1%3A3307/app/view/a%2Fb%20c.sql"},
+		{"primary", Definition{Schema: "app", Name: "..", Type: "EVENT"}, "out/primary/app/event/%2E..sql"},
+	}
+	for _, tt := range tests {
+		if got := filepath.ToSlash(ExportPath("out", tt.host, tt.def)); got != tt.want {
+			t.Errorf("ExportPath(%q, %q) = %q, want %q", tt.host, tt.def.Name, got, tt.want)
+		}
+	}
+}
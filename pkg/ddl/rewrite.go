@@ -0,0 +1,197 @@
+package ddl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// RewriteStep is the DDL that moves one object to a new definer
+type RewriteStep struct {
+	Schema  string
+	Name    string
+	Type    string
+	Definer string // current definer, as INFORMATION_SCHEMA reports it
+	// Setup restores the session settings the object was created under
+	Setup []string
+	// Statements is the DDL itself; for DROP+CREATE the last one is the CREATE
+	Statements []string
+	// Original recreates the object as it was if the CREATE fails after the DROP
+	Original string
+}
+
+// ReplaceDefiner swaps the DEFINER clause at the start of a SHOW CREATE statement
+func ReplaceDefiner(createStmt, definer string) (string, error) {
+	i := 0
+	skipSpace := func() {
+		for i < len(createStmt) && strings.ContainsRune(" \t\r\n", rune(createStmt[i])) {
+			i++
+		}
+	}
+	// skipUserPart steps over a quoted or bare user or host name
+	skipUserPart := func() {
+		if i < len(createStmt) && strings.ContainsRune("`'\"", rune(createStmt[i])) {
+			q := createStmt[i]
+			i++
+			for i < len(createStmt) {
+				if createStmt[i] == q {
+					if i+1 < len(createStmt) && createStmt[i+1] == q {
+						i += 2
+						continue
+					}
+					i++
+					return
+				}
+				i++
+			}
+			return
+		}
+		for i < len(createStmt) && !strings.ContainsRune(" \t\r\n@", rune(createStmt[i])) {
+			i++
+		}
+	}
+
+	skipSpace()
+	if !strings.HasPrefix(strings.ToUpper(createStmt[i:]), "CREATE") {
+		return "", fmt.Errorf("statement does not start with CREATE")
+	}
+	i += len("CREATE")
+	skipSpace()
+	if !strings.HasPrefix(strings.ToUpper(createStmt[i:]), "DEFINER") {
+		return "", fmt.Errorf("statement has no DEFINER clause")
+	}
+	start := i
+	i += len("DEFINER")
+	skipSpace()
+	if i >= len(createStmt) || createStmt[i] != '=' {
+		return "", fmt.Errorf("could not parse DEFINER clause")
+	}
+	i++
+	skipSpace()
+	skipUserPart()
+	if i >= len(createStmt) || createStmt[i] != '@' {
+		return "", fmt.Errorf("could not parse DEFINER clause")
+	}
+	i++
+	skipUserPart()
+
+	return createStmt[:start] + "DEFINER=" + definer + createStmt[i:], nil
+}
+
+// PlanDefinerRewrite builds the DDL that moves every object in schema owned
+// by from to to. Both are user@host specs.
+func PlanDefinerRewrite(ctx context.Context, db *sql.DB, schema, from, to string) ([]RewriteStep, error) {
+	fromUser, fromHost, err := inventory.ParseDefiner(from)
+	if err != nil {
+		return nil, err
+	}
+	toUser, toHost, err := inventory.ParseDefiner(to)
+	if err != nil {
+		return nil, err
+	}
+	newDefiner := QuoteDefiner(toUser, toHost)
+
+	objects, err := inventory.Objects(ctx, db, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []RewriteStep
+	for _, object := range objects {
+		user, host := inventory.SplitDefiner(object.Definer)
+		if user != fromUser || !strings.EqualFold(host, fromHost) {
+			continue
+		}
+
+		def, err := ShowCreateOfType(ctx, db, schema, object.Name, object.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		step := RewriteStep{Schema: schema, Name: object.Name, Type: object.Type, Definer: object.Definer}
+		step.Setup = append(step.Setup, fmt.Sprintf("USE %s", sqlparse.QuoteIdentifier(schema)))
+		if def.SQLMode != "" || object.Type != "VIEW" {
+			step.Setup = append(step.Setup, fmt.Sprintf("SET SESSION sql_mode = %s", QuoteString(def.SQLMode)))
+		}
+		if def.CharacterSetClient != "" {
+			step.Setup = append(step.Setup, fmt.Sprintf("SET SESSION character_set_client = %s", QuoteString(def.CharacterSetClient)))
+		}
+		if def.CollationConnection != "" {
+			step.Setup = append(step.Setup, fmt.Sprintf("SET SESSION collation_connection = %s", QuoteString(def.CollationConnection)))
+		}
+
+		qualified := sqlparse.QuoteIdentifier(schema) + "." + sqlparse.QuoteIdentifier(object.Name)
+		switch object.Type {
+		case "VIEW":
+			view, err := ParseView(schema, object.Name, def.CreateStatement)
+			if err != nil {
+				return nil, fmt.Errorf("view %s: %v", object.Name, err)
+			}
+			view.DefinerUser, view.DefinerHost = toUser, toHost
+			step.Statements = []string{strings.TrimSuffix(view.AlterStatement(), ";")}
+		case "EVENT":
+			// ALTER EVENT needs at least one clause besides DEFINER, so the
+			// existing comment is restated, which leaves the event unchanged
+			var comment string
+			err := db.QueryRowContext(ctx, "SELECT EVENT_COMMENT FROM INFORMATION_SCHEMA.EVENTS WHERE EVENT_SCHEMA = ? AND EVENT_NAME = ?",
+				schema, object.Name).Scan(&comment)
+			if err != nil {
+				return nil, fmt.Errorf("error reading event %s: %v", object.Name, err)
+			}
+			step.Statements = []string{fmt.Sprintf("ALTER DEFINER = %s EVENT %s COMMENT %s", newDefiner, qualified, QuoteString(comment))}
+		case "PROCEDURE", "FUNCTION", "TRIGGER":
+			create, err := ReplaceDefiner(def.CreateStatement, newDefiner)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %v", strings.ToLower(object.Type), object.Name, err)
+			}
+			step.Statements = []string{fmt.Sprintf("DROP %s IF EXISTS %s", object.Type, qualified), create}
+			step.Original = def.CreateStatement
+		default:
+			return nil, fmt.Errorf("unknown object type '%s' for '%s'", object.Type, object.Name)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// Execute runs the step on a single connection so the session settings apply
+// to the DDL, and restores the connection's settings after.
+func (step RewriteStep) Execute(ctx context.Context, db *sql.DB) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var sqlMode, charsetClient, collation string
+	err = conn.QueryRowContext(ctx, "SELECT @@SESSION.sql_mode, @@SESSION.character_set_client, @@SESSION.collation_connection").
+		Scan(&sqlMode, &charsetClient, &collation)
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, fmt.Sprintf("SET SESSION sql_mode = %s, character_set_client = %s, collation_connection = %s",
+		QuoteString(sqlMode), QuoteString(charsetClient), QuoteString(collation)))
+
+	for _, stmt := range step.Setup {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%s: %v", stmt, err)
+		}
+	}
+	for i, stmt := range step.Statements {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			// The object was already dropped, so put the original back
+			if i > 0 && step.Original != "" {
+				if _, restoreErr := conn.ExecContext(ctx, step.Original); restoreErr != nil {
+					return fmt.Errorf("%v; restoring the original definition also failed: %v", err, restoreErr)
+				}
+				return fmt.Errorf("%v (original definition restored)", err)
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package ddl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ChaosHour/go-pvt/pkg/internal/fakedb"
+)
+
+func TestRewriteStepExecuteRestoresOriginal(t *testing.T) {
+	db, server := fakedb.Open(t,
+		fakedb.Result{Match: "CREATE DEFINER=`svc`", Err: errors.New("Error 1227: Access denied; you need the SUPER privilege")},
+		fakedb.Result{
+			Match:   "SELECT @@SESSION.sql_mode",
+			Columns: []string{"@@SESSION.sql_mode", "@@SESSION.character_set_client", "@@SESSION.collation_connection"},
+			Rows:    [][]interface{}{{"", "latin1", "latin1_swedish_ci"}},
+		},
+	)
+	step := RewriteStep{
+		Schema: "app", Name: "orders_ai", Type: "TRIGGER", Definer: "app@%",
+		Setup: []string{"USE `app`", "SET SESSION sql_mode = 'STRICT_TRANS_TABLES'"},
+		Statements: []string{
+			"DROP TRIGGER IF EXISTS `app`.`orders_ai`",
+			"CREATE DEFINER=`svc`@`%` TRIGGER `orders_ai` AFTER INSERT ON `orders` FOR EACH ROW SET @n = @n + 1",
+		},
+		Original: "CREATE DEFINER=`app`@`%` TRIGGER `orders_ai` AFTER INSERT ON `orders` FOR EACH ROW SET @n = @n + 1",
+	}
+
+	err := step.Execute(context.Background(), db)
+	if err == nil || !strings.Contains(err.Error(), "original definition restored") {
+		t.Errorf("Execute() error = %v, want the original definition restored", err)
+	}
+	want := append(append(append([]string(nil), step.Setup...), step.Statements...), step.Original,
+		"SET SESSION sql_mode = '', character_set_client = 'latin1', collation_connection = 'latin1_swedish_ci'")
+	if got := server.Executed(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Execute() ran\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
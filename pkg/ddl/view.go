@@ -0,0 +1,209 @@
+package ddl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// ParseView parses a SHOW CREATE VIEW statement and qualifies it with its schema
+func ParseView(schema, name, createStmt string) (*sqlparse.CreateView, error) {
+	view, err := sqlparse.ParseCreateView(createStmt)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse view definition: %v", err)
+	}
+	view.Schema = schema
+	view.Name = name
+	return view, nil
+}
+
+// ValidateAlgorithm upper-cases and checks a view algorithm
+func ValidateAlgorithm(algorithm string) (string, error) {
+	algorithm = strings.ToUpper(algorithm)
+	if algorithm != "MERGE" && algorithm != "TEMPTABLE" && algorithm != "UNDEFINED" {
+		return "", fmt.Errorf("invalid algorithm: %s. Must be MERGE, TEMPTABLE, or UNDEFINED", algorithm)
+	}
+	return algorithm, nil
+}
+
+// AlterViewAlgorithm generates an ALTER VIEW statement that changes only the algorithm
+func AlterViewAlgorithm(ctx context.Context, db *sql.DB, schema, viewName, algorithm string) (string, error) {
+	def, err := ShowCreateOfType(ctx, db, schema, viewName, "VIEW")
+	if err != nil {
+		return "", fmt.Errorf("error getting view definition: %v", err)
+	}
+
+	view, err := ParseView(schema, viewName, def.CreateStatement)
+	if err != nil {
+		return "", err
+	}
+	view.Algorithm = algorithm
+
+	return view.AlterStatement(), nil
+}
+
+// ViewSelector narrows the views SelectViews returns. Zero values match everything.
+type ViewSelector struct {
+	Filter     inventory.Filter // names and definer; views are skipped if its types exclude VIEW
+	Algorithms []string         // current algorithms, e.g. TEMPTABLE
+}
+
+// ViewChange is one view selected for an algorithm change
+type ViewChange struct {
+	Name                string
+	Definer             string
+	Algorithm           string // current algorithm
+	CharacterSetClient  string
+	CollationConnection string
+	CreateStatement     string
+	AlterStatement      string
+	MergeBlockers       []string // what keeps MySQL from merging the view, empty if it can
+	Err                 error    // set by the caller after executing AlterStatement
+}
+
+// SelectViews returns the views of schema matching sel, with an ALTER VIEW
+// to algorithm for each. Views already using algorithm are skipped.
+func SelectViews(ctx context.Context, db *sql.DB, schema, algorithm string, sel ViewSelector) ([]ViewChange, error) {
+	if !sel.Filter.HasType("VIEW") {
+		return nil, nil
+	}
+	filter := sel.Filter
+	filter.Types = []string{"VIEW"}
+	objects, err := inventory.ObjectsMatching(ctx, db, schema, filter)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	candidates := make([]ViewChange, len(objects))
+	for i, o := range objects {
+		candidates[i] = ViewChange{Name: o.Name, Definer: o.Definer}
+	}
+
+	wanted := make(map[string]bool)
+	for _, algo := range sel.Algorithms {
+		if algo = strings.ToUpper(strings.TrimSpace(algo)); algo != "" {
+			wanted[algo] = true
+		}
+	}
+
+	// The algorithm is only reported by SHOW CREATE VIEW
+	var views []ViewChange
+	for _, view := range candidates {
+		def, err := ShowCreateOfType(ctx, db, schema, view.Name, "VIEW")
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParseView(schema, view.Name, def.CreateStatement)
+		if err != nil {
+			return nil, fmt.Errorf("view %s: %v", view.Name, err)
+		}
+		view.Algorithm = parsed.Algorithm
+		view.MergeBlockers = parsed.MergeBlockers()
+		view.CreateStatement = def.CreateStatement
+		view.CharacterSetClient = def.CharacterSetClient
+		view.CollationConnection = def.CollationConnection
+		if len(wanted) > 0 && !wanted[view.Algorithm] {
+			continue
+		}
+		if view.Algorithm == algorithm {
+			continue
+		}
+		parsed.Algorithm = algorithm
+		view.AlterStatement = parsed.AlterStatement()
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// AlgorithmResult is what an ALTER VIEW setting an algorithm achieved
+type AlgorithmResult struct {
+	Requested string
+	Effective string   // the algorithm SHOW CREATE VIEW reports afterwards
+	Warnings  []string // SHOW WARNINGS right after the ALTER
+}
+
+// Honoured reports whether the view got the requested algorithm. MySQL
+// stores a view that cannot be merged with ALGORITHM=UNDEFINED instead of
+// MERGE, and only says so in a warning.
+func (r AlgorithmResult) Honoured() bool {
+	return r.Effective == r.Requested
+}
+
+// ApplyViewAlgorithm executes alterStmt, which sets the algorithm of view
+// schema.name to requested, and reads back the warnings it raised and the
+// algorithm the view ended up with. ALTER VIEW stores the session's
+// character_set_client and collation_connection with the view, so the
+// statement runs under the view's own settings, as RewriteStep.Execute does,
+// and the connection's settings are restored after.
+func ApplyViewAlgorithm(ctx context.Context, db *sql.DB, schema, name, requested, alterStmt string) (AlgorithmResult, error) {
+	result := AlgorithmResult{Requested: requested}
+
+	before, err := ShowCreateOfType(ctx, db, schema, name, "VIEW")
+	if err != nil {
+		return result, err
+	}
+
+	// Warnings belong to the session, so read them on the same connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+
+	var charsetClient, collation string
+	err = conn.QueryRowContext(ctx, "SELECT @@SESSION.character_set_client, @@SESSION.collation_connection").
+		Scan(&charsetClient, &collation)
+	if err != nil {
+		return result, err
+	}
+	defer conn.ExecContext(ctx, fmt.Sprintf("SET SESSION character_set_client = %s, collation_connection = %s",
+		QuoteString(charsetClient), QuoteString(collation)))
+
+	var settings []string
+	if before.CharacterSetClient != "" {
+		settings = append(settings, "character_set_client = "+QuoteString(before.CharacterSetClient))
+	}
+	if before.CollationConnection != "" {
+		settings = append(settings, "collation_connection = "+QuoteString(before.CollationConnection))
+	}
+	if len(settings) > 0 {
+		if _, err := conn.ExecContext(ctx, "SET SESSION "+strings.Join(settings, ", ")); err != nil {
+			return result, err
+		}
+	}
+	if _, err := conn.ExecContext(ctx, alterStmt); err != nil {
+		return result, err
+	}
+	rows, err := conn.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var level, message string
+		var code int
+		if err := rows.Scan(&level, &code, &message); err != nil {
+			return result, err
+		}
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%s %d: %s", level, code, message))
+	}
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	def, err := ShowCreateOfType(ctx, db, schema, name, "VIEW")
+	if err != nil {
+		return result, err
+	}
+	view, err := ParseView(schema, name, def.CreateStatement)
+	if err != nil {
+		return result, err
+	}
+	result.Effective = view.Algorithm
+	return result, nil
+}
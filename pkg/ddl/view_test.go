@@ -0,0 +1,49 @@
+package ddl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ChaosHour/go-pvt/pkg/internal/fakedb"
+)
+
+func TestApplyViewAlgorithm(t *testing.T) {
+	db, server := fakedb.Open(t,
+		fakedb.Result{
+			Match:   "SHOW CREATE VIEW",
+			Columns: []string{"View", "Create View", "character_set_client", "collation_connection"},
+			Rows: [][]interface{}{{"v", "CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `v` AS select count(0) AS `n` from `t`",
+				"latin1", "latin1_german1_ci"}},
+		},
+		fakedb.Result{
+			Match:   "SELECT @@SESSION.character_set_client",
+			Columns: []string{"@@SESSION.character_set_client", "@@SESSION.collation_connection"},
+			Rows:    [][]interface{}{{"utf8mb4", "utf8mb4_0900_ai_ci"}},
+		},
+		fakedb.Result{
+			Match:   "SHOW WARNINGS",
+			Columns: []string{"Level", "Code", "Message"},
+			Rows:    [][]interface{}{{"Warning", "1354", "View merge algorithm can't be used here for now (assumed undefined algorithm)"}},
+		},
+	)
+
+	alter := "ALTER ALGORITHM=MERGE DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `app`.`v` AS select count(0) AS `n` from `t`"
+	result, err := ApplyViewAlgorithm(context.Background(), db, "app", "v", "MERGE", alter)
+	if err != nil {
+		t.Fatalf("ApplyViewAlgorithm() error: %v", err)
+	}
+	if result.Honoured() || result.Effective != "UNDEFINED" || len(result.Warnings) != 1 || !strings.HasPrefix(result.Warnings[0], "Warning 1354:") {
+		t.Errorf("ApplyViewAlgorithm() = %+v", result)
+	}
+
+	// The view keeps the settings it was created under
+	want := []string{
+		"SET SESSION character_set_client = 'latin1', collation_connection = 'latin1_german1_ci'",
+		alter,
+		"SET SESSION character_set_client = 'utf8mb4', collation_connection = 'utf8mb4_0900_ai_ci'",
+	}
+	if got := server.Executed(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ApplyViewAlgorithm() ran\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
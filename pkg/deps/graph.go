@@ -0,0 +1,305 @@
+// Package deps builds the graph of which views, routines, triggers and
+// events use which tables, views and routines, and answers what depends on
+// an object and in which order objects can be recreated.
+package deps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Node is an object of the graph. Type is empty for a table or view that is
+// referenced but was not loaded.
+type Node struct {
+	Schema   string `json:"schema"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Missing  bool   `json:"missing,omitempty"`  // referenced, but not found in its schema
+	External bool   `json:"external,omitempty"` // in a schema that was not loaded
+}
+
+// loaded reports whether n is one of the objects the graph was built from
+func (n Node) loaded() bool {
+	return !n.Missing && !n.External
+}
+
+// String renders the node as schema.name
+func (n Node) String() string {
+	return n.Schema + "." + n.Name
+}
+
+// namespace returns the group of types whose names must be unique within a
+// schema: tables and views share one, every other type has its own
+func namespace(objectType string) string {
+	switch objectType {
+	case "TABLE", "VIEW", "":
+		return "TABLE"
+	}
+	return objectType
+}
+
+// key identifies a node. MySQL may fold table name case, and never
+// distinguishes routine names by case, so names are compared without it.
+func key(n Node) string {
+	return namespace(n.Type) + "\x00" + strings.ToLower(n.Schema) + "\x00" + strings.ToLower(n.Name)
+}
+
+// Edge records that From uses To
+type Edge struct {
+	From Node
+	To   Node
+}
+
+// Graph is a set of objects and the objects each of them uses
+type Graph struct {
+	nodes  map[string]Node
+	uses   map[string][]string
+	usedBy map[string][]string
+}
+
+// NewGraph returns an empty graph
+func NewGraph() *Graph {
+	return &Graph{nodes: make(map[string]Node), uses: make(map[string][]string), usedBy: make(map[string][]string)}
+}
+
+// AddNode adds an object. Adding a loaded object replaces a Missing or
+// External node of the same name that was added for a reference to it.
+func (g *Graph) AddNode(n Node) {
+	k := key(n)
+	if old, ok := g.nodes[k]; ok && (old.loaded() || !n.loaded()) {
+		return
+	}
+	g.nodes[k] = n
+}
+
+// Lookup returns the node for an object of the given type, or a table or
+// view when objectType is empty
+func (g *Graph) Lookup(schema, name, objectType string) (Node, bool) {
+	n, ok := g.nodes[key(Node{Schema: schema, Name: name, Type: objectType})]
+	return n, ok
+}
+
+// AddEdge records that from uses to, adding either node if needed. An
+// object using itself, such as a recursive procedure, is not recorded.
+func (g *Graph) AddEdge(from, to Node) {
+	g.AddNode(from)
+	g.AddNode(to)
+	f, t := key(from), key(to)
+	if f == t {
+		return
+	}
+	for _, existing := range g.uses[f] {
+		if existing == t {
+			return
+		}
+	}
+	g.uses[f] = append(g.uses[f], t)
+	g.usedBy[t] = append(g.usedBy[t], f)
+}
+
+// Nodes returns every node, by schema, type and name
+func (g *Graph) Nodes() []Node {
+	nodes := make([]Node, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+	sortNodes(nodes)
+	return nodes
+}
+
+// Find returns the loaded objects named name in schema, of any type
+func (g *Graph) Find(schema, name string) []Node {
+	var found []Node
+	for _, n := range g.nodes {
+		if n.loaded() && strings.EqualFold(n.Schema, schema) && strings.EqualFold(n.Name, name) {
+			found = append(found, n)
+		}
+	}
+	sortNodes(found)
+	return found
+}
+
+// Uses returns the objects n uses directly
+func (g *Graph) Uses(n Node) []Node {
+	return g.lookup(g.uses[key(n)])
+}
+
+// UsedBy returns the objects that use n directly
+func (g *Graph) UsedBy(n Node) []Node {
+	return g.lookup(g.usedBy[key(n)])
+}
+
+func (g *Graph) lookup(keys []string) []Node {
+	nodes := make([]Node, len(keys))
+	for i, k := range keys {
+		nodes[i] = g.nodes[k]
+	}
+	sortNodes(nodes)
+	return nodes
+}
+
+// Reachable returns roots and every object they use, directly or
+// indirectly, or with reverse every object that uses them. Nodes are in the
+// order they are reached.
+func (g *Graph) Reachable(roots []Node, reverse bool) []Node {
+	next := g.uses
+	if reverse {
+		next = g.usedBy
+	}
+	seen := make(map[string]bool)
+	var nodes []Node
+	queue := make([]string, 0, len(roots))
+	for _, n := range roots {
+		queue = append(queue, key(n))
+	}
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		nodes = append(nodes, g.nodes[k])
+		queue = append(queue, g.lookupKeys(next[k])...)
+	}
+	return nodes
+}
+
+// lookupKeys returns keys in the order of their sorted nodes, so traversals
+// do not depend on the order edges were added in
+func (g *Graph) lookupKeys(keys []string) []string {
+	sorted := make([]string, 0, len(keys))
+	for _, n := range g.lookup(keys) {
+		sorted = append(sorted, key(n))
+	}
+	return sorted
+}
+
+// Dependents returns every object that uses n, directly or indirectly:
+// what breaks if n is dropped
+func (g *Graph) Dependents(n Node) []Node {
+	reached := g.Reachable([]Node{n}, true)
+	return reached[1:]
+}
+
+// Edges returns the edges between the given nodes, by user and then used
+// object
+func (g *Graph) Edges(nodes []Node) []Edge {
+	in := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		in[key(n)] = true
+	}
+	from := append([]Node(nil), nodes...)
+	sortNodes(from)
+
+	var edges []Edge
+	for _, f := range from {
+		for _, t := range g.Uses(f) {
+			if in[key(t)] {
+				edges = append(edges, Edge{From: f, To: t})
+			}
+		}
+	}
+	return edges
+}
+
+// CycleError is returned by Order when objects depend on each other
+type CycleError struct {
+	Nodes []Node // the objects in or after the cycle
+}
+
+func (e *CycleError) Error() string {
+	names := make([]string, len(e.Nodes))
+	for i, n := range e.Nodes {
+		names[i] = n.String()
+	}
+	return fmt.Sprintf("dependency cycle between %s", strings.Join(names, ", "))
+}
+
+// Order returns nodes so that every object comes after the objects it uses,
+// directly or through objects that are not in nodes. Otherwise the input
+// order is kept. Objects that depend on each other are a *CycleError.
+func (g *Graph) Order(nodes []Node) ([]Node, error) {
+	// Rank the nodes and everything they use, the nodes first
+	rank := make(map[string]int)
+	var all []string
+	add := func(k string) {
+		if _, ok := rank[k]; !ok {
+			rank[k] = len(all)
+			all = append(all, k)
+		}
+	}
+	for _, n := range nodes {
+		add(key(n))
+	}
+	for i := 0; i < len(all); i++ {
+		for _, k := range g.lookupKeys(g.uses[all[i]]) {
+			add(k)
+		}
+	}
+
+	pending := make(map[string]int, len(all))
+	var ready []string
+	for _, k := range all {
+		pending[k] = len(g.uses[k])
+		if pending[k] == 0 {
+			ready = append(ready, k)
+		}
+	}
+
+	// Kahn's algorithm, always taking the earliest ranked ready node to stay stable
+	selected := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		selected[key(n)] = true
+	}
+	var ordered []Node
+	done := 0
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool { return rank[ready[i]] < rank[ready[j]] })
+		k := ready[0]
+		ready = ready[1:]
+		done++
+		if selected[k] {
+			ordered = append(ordered, g.nodes[k])
+			selected[k] = false
+		}
+		for _, u := range g.usedBy[k] {
+			if _, ok := rank[u]; !ok {
+				continue
+			}
+			pending[u]--
+			if pending[u] == 0 {
+				ready = append(ready, u)
+			}
+		}
+	}
+
+	if done < len(all) {
+		cycle := &CycleError{}
+		for _, k := range all {
+			if pending[k] > 0 {
+				cycle.Nodes = append(cycle.Nodes, g.nodes[k])
+			}
+		}
+		return nil, cycle
+	}
+	return ordered, nil
+}
+
+// typeOrder ranks types for listings, from the objects others build on to
+// those that build on them
+var typeOrder = map[string]int{"TABLE": 0, "VIEW": 1, "FUNCTION": 2, "PROCEDURE": 3, "TRIGGER": 4, "EVENT": 5, "": 6}
+
+func sortNodes(nodes []Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if a.Schema != b.Schema {
+			return a.Schema < b.Schema
+		}
+		if a.Type != b.Type {
+			return typeOrder[a.Type] < typeOrder[b.Type]
+		}
+		return a.Name < b.Name
+	})
+}
@@ -0,0 +1,118 @@
+package deps
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testGraph: two views over orders, a function used by one of them, a
+// procedure calling the function and a trigger on orders
+func testGraph() *Graph {
+	node := func(t, name string) Node { return Node{Schema: "app", Name: name, Type: t} }
+	g := NewGraph()
+	g.AddEdge(node("VIEW", "v_totals"), node("VIEW", "v_orders"))
+	g.AddEdge(node("VIEW", "v_orders"), node("TABLE", "orders"))
+	g.AddEdge(node("VIEW", "v_orders"), node("FUNCTION", "fmt_money"))
+	g.AddEdge(node("PROCEDURE", "report"), node("FUNCTION", "fmt_money"))
+	g.AddEdge(node("PROCEDURE", "report"), node("", "gone"))
+	g.AddEdge(node("TRIGGER", "orders_ai"), node("TABLE", "orders"))
+	g.AddEdge(node("TRIGGER", "orders_ai"), Node{Schema: "audit", Name: "log", External: true})
+	g.AddEdge(node("PROCEDURE", "report"), node("PROCEDURE", "report"))
+	return g
+}
+
+func names(nodes []Node) string {
+	s := make([]string, len(nodes))
+	for i, n := range nodes {
+		s[i] = n.String()
+	}
+	return strings.Join(s, " ")
+}
+
+func TestDependents(t *testing.T) {
+	g := testGraph()
+	orders, _ := g.Lookup("app", "ORDERS", "")
+	if got, want := names(g.Dependents(orders)), "app.v_orders app.orders_ai app.v_totals"; got != want {
+		t.Errorf("Dependents(orders) = %s, want %s", got, want)
+	}
+	fn, _ := g.Lookup("app", "fmt_money", "FUNCTION")
+	if got, want := names(g.Dependents(fn)), "app.v_orders app.report app.v_totals"; got != want {
+		t.Errorf("Dependents(fmt_money) = %s, want %s", got, want)
+	}
+	if found := g.Find("app", "report"); len(found) != 1 || found[0].Type != "PROCEDURE" {
+		t.Errorf("Find(report) = %v", found)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	g := testGraph()
+	var views []Node
+	for _, name := range []string{"v_totals", "v_orders"} {
+		n, _ := g.Lookup("app", name, "VIEW")
+		views = append(views, n)
+	}
+	ordered, err := g.Order(views)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(ordered), "app.v_orders app.v_totals"; got != want {
+		t.Errorf("Order() = %s, want %s", got, want)
+	}
+
+	a, b := Node{Schema: "app", Name: "a", Type: "VIEW"}, Node{Schema: "app", Name: "b", Type: "VIEW"}
+	g.AddEdge(a, b)
+	g.AddEdge(b, a)
+	var cycle *CycleError
+	if _, err := g.Order([]Node{a}); !errors.As(err, &cycle) || names(cycle.Nodes) != "app.a app.b" {
+		t.Errorf("Order() error = %v, want a cycle between app.a and app.b", err)
+	}
+}
+
+func TestWriteTree(t *testing.T) {
+	g := testGraph()
+	var roots []Node
+	for _, n := range g.Nodes() {
+		if n.Type == "VIEW" || n.Type == "PROCEDURE" {
+			roots = append(roots, n)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteTree(&buf, g, roots, false); err != nil {
+		t.Fatal(err)
+	}
+	want := `VIEW app.v_orders
+├── TABLE app.orders
+└── FUNCTION app.fmt_money
+VIEW app.v_totals
+└── VIEW app.v_orders (see above)
+PROCEDURE app.report
+├── FUNCTION app.fmt_money
+└── TABLE? app.gone
+`
+	if buf.String() != want {
+		t.Errorf("WriteTree() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	g := testGraph()
+	trigger, _ := g.Lookup("app", "orders_ai", "TRIGGER")
+	var buf bytes.Buffer
+	if err := WriteDOT(&buf, g, g.Reachable([]Node{trigger}, false)); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph dependencies {
+	rankdir=LR;
+	"table:app.orders" [label="app.orders\ntable", shape=box];
+	"trigger:app.orders_ai" [label="app.orders_ai\ntrigger", shape=hexagon];
+	"table:audit.log" [label="audit.log", shape=box, style=dashed];
+	"trigger:app.orders_ai" -> "table:app.orders";
+	"trigger:app.orders_ai" -> "table:audit.log";
+}
+`
+	if buf.String() != want {
+		t.Errorf("WriteDOT() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,264 @@
+package deps
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// loader builds a graph from the objects of some schemas
+type loader struct {
+	g       *Graph
+	schemas map[string]bool // lower-cased names of the loaded schemas
+}
+
+// Load builds the dependency graph of the objects in schemas. Views are
+// linked through INFORMATION_SCHEMA.VIEW_TABLE_USAGE and VIEW_ROUTINE_USAGE
+// where the server has them (MySQL 8.0), and by parsing their queries
+// otherwise. Routines, triggers and events are always parsed, so references
+// built with dynamic SQL are not seen. Triggers use the table they are
+// defined on.
+func Load(ctx context.Context, db *sql.DB, schemas []string) (*Graph, error) {
+	l := &loader{g: NewGraph(), schemas: make(map[string]bool)}
+	all := inventory.Filter{Types: inventory.ObjectTypes}
+	for _, schema := range schemas {
+		l.schemas[strings.ToLower(schema)] = true
+		objects, err := inventory.ObjectsMatching(ctx, db, schema, all)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objects {
+			l.g.AddNode(Node{Schema: o.Schema, Name: o.Name, Type: o.Type})
+		}
+	}
+
+	in, args := placeholders(schemas)
+	tableUsage, err := hasInformationSchemaTable(ctx, db, "VIEW_TABLE_USAGE")
+	if err != nil {
+		return nil, err
+	}
+	routineUsage, err := hasInformationSchemaTable(ctx, db, "VIEW_ROUTINE_USAGE")
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []func(context.Context, *sql.DB, string, []interface{}) error{}
+	if tableUsage {
+		steps = append(steps, l.viewTableUsage)
+	}
+	if routineUsage {
+		steps = append(steps, l.viewRoutineUsage)
+	}
+	if !tableUsage || !routineUsage {
+		parse := func(ctx context.Context, db *sql.DB, in string, args []interface{}) error {
+			return l.views(ctx, db, in, args, !tableUsage, !routineUsage)
+		}
+		steps = append(steps, parse)
+	}
+	steps = append(steps, l.routines, l.triggers, l.events)
+	for _, step := range steps {
+		if err := step(ctx, db, in, args); err != nil {
+			return nil, err
+		}
+	}
+	return l.g, nil
+}
+
+// placeholders returns "(?, ?)" and the arguments for an IN list of schemas
+func placeholders(schemas []string) (string, []interface{}) {
+	marks := make([]string, len(schemas))
+	args := make([]interface{}, len(schemas))
+	for i, schema := range schemas {
+		marks[i] = "?"
+		args[i] = schema
+	}
+	return "(" + strings.Join(marks, ", ") + ")", args
+}
+
+// hasInformationSchemaTable reports whether the server has the given
+// INFORMATION_SCHEMA table. MySQL 5.7 and MariaDB lack the view usage tables.
+func hasInformationSchemaTable(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `
+        SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES
+        WHERE TABLE_SCHEMA = 'information_schema' AND TABLE_NAME = ?`, table).Scan(&n)
+	return n > 0, err
+}
+
+// resolve returns the node a reference from an object in schema points to.
+// Unqualified names are in the object's own schema. A reference into a
+// schema that was not loaded is External; one to an object not found in a
+// loaded schema is Missing, unless it was found by parsing, since the parser
+// also sees temporary tables, CTEs and built-in functions.
+func (l *loader) resolve(schema, ref, objectType string, parsed bool) (Node, bool) {
+	name := ref
+	if s, n, ok := strings.Cut(ref, "."); ok {
+		schema, name = s, n
+	}
+	if n, ok := l.g.Lookup(schema, name, objectType); ok {
+		return n, true
+	}
+	if !l.schemas[strings.ToLower(schema)] {
+		return Node{Schema: schema, Name: name, Type: objectType, External: true}, true
+	}
+	if parsed {
+		return Node{}, false
+	}
+	return Node{Schema: schema, Name: name, Type: objectType, Missing: true}, true
+}
+
+// addParsed links from to the tables, views and routines stmt references
+func (l *loader) addParsed(from Node, stmt string, tables, routines bool) {
+	if tables {
+		if refs, err := sqlparse.ReferencedTables(stmt); err == nil {
+			for _, ref := range refs {
+				if to, ok := l.resolve(from.Schema, ref, "", true); ok {
+					l.g.AddEdge(from, to)
+				}
+			}
+		}
+	}
+	if !routines {
+		return
+	}
+	procedures, functions, err := sqlparse.ReferencedRoutines(stmt)
+	if err != nil {
+		return
+	}
+	for _, ref := range procedures {
+		if to, ok := l.resolve(from.Schema, ref, "PROCEDURE", true); ok {
+			l.g.AddEdge(from, to)
+		}
+	}
+	for _, ref := range functions {
+		// Only qualified names can be told apart from built-in functions
+		// when their schema was not loaded
+		if !strings.Contains(ref, ".") {
+			if _, ok := l.g.Lookup(from.Schema, ref, "FUNCTION"); !ok {
+				continue
+			}
+		}
+		if to, ok := l.resolve(from.Schema, ref, "FUNCTION", true); ok {
+			l.g.AddEdge(from, to)
+		}
+	}
+}
+
+func (l *loader) viewTableUsage(ctx context.Context, db *sql.DB, in string, args []interface{}) error {
+	rows, err := db.QueryContext(ctx, `
+        SELECT VIEW_SCHEMA, VIEW_NAME, TABLE_SCHEMA, TABLE_NAME
+        FROM INFORMATION_SCHEMA.VIEW_TABLE_USAGE WHERE VIEW_SCHEMA IN `+in, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var viewSchema, viewName, tableSchema, tableName string
+		if err := rows.Scan(&viewSchema, &viewName, &tableSchema, &tableName); err != nil {
+			return err
+		}
+		to, _ := l.resolve(tableSchema, tableName, "", false)
+		l.g.AddEdge(Node{Schema: viewSchema, Name: viewName, Type: "VIEW"}, to)
+	}
+	return rows.Err()
+}
+
+func (l *loader) viewRoutineUsage(ctx context.Context, db *sql.DB, in string, args []interface{}) error {
+	rows, err := db.QueryContext(ctx, `
+        SELECT TABLE_SCHEMA, TABLE_NAME, SPECIFIC_SCHEMA, SPECIFIC_NAME
+        FROM INFORMATION_SCHEMA.VIEW_ROUTINE_USAGE WHERE TABLE_SCHEMA IN `+in, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var viewSchema, viewName, routineSchema, routineName string
+		if err := rows.Scan(&viewSchema, &viewName, &routineSchema, &routineName); err != nil {
+			return err
+		}
+		to, _ := l.resolve(routineSchema, routineName, "FUNCTION", false)
+		l.g.AddEdge(Node{Schema: viewSchema, Name: viewName, Type: "VIEW"}, to)
+	}
+	return rows.Err()
+}
+
+// views parses the queries of views for the references the server does not list
+func (l *loader) views(ctx context.Context, db *sql.DB, in string, args []interface{}, tables, routines bool) error {
+	rows, err := db.QueryContext(ctx, `
+        SELECT TABLE_SCHEMA, TABLE_NAME, VIEW_DEFINITION
+        FROM INFORMATION_SCHEMA.VIEWS WHERE TABLE_SCHEMA IN `+in, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schema, name string
+		var definition sql.NullString
+		if err := rows.Scan(&schema, &name, &definition); err != nil {
+			return err
+		}
+		l.addParsed(Node{Schema: schema, Name: name, Type: "VIEW"}, definition.String, tables, routines)
+	}
+	return rows.Err()
+}
+
+func (l *loader) routines(ctx context.Context, db *sql.DB, in string, args []interface{}) error {
+	rows, err := db.QueryContext(ctx, `
+        SELECT ROUTINE_SCHEMA, ROUTINE_NAME, ROUTINE_TYPE, ROUTINE_DEFINITION
+        FROM INFORMATION_SCHEMA.ROUTINES WHERE ROUTINE_SCHEMA IN `+in, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schema, name, routineType string
+		var definition sql.NullString
+		if err := rows.Scan(&schema, &name, &routineType, &definition); err != nil {
+			return err
+		}
+		l.addParsed(Node{Schema: schema, Name: name, Type: routineType}, definition.String, true, true)
+	}
+	return rows.Err()
+}
+
+func (l *loader) triggers(ctx context.Context, db *sql.DB, in string, args []interface{}) error {
+	rows, err := db.QueryContext(ctx, `
+        SELECT TRIGGER_SCHEMA, TRIGGER_NAME, EVENT_OBJECT_SCHEMA, EVENT_OBJECT_TABLE, ACTION_STATEMENT
+        FROM INFORMATION_SCHEMA.TRIGGERS WHERE TRIGGER_SCHEMA IN `+in, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schema, name, tableSchema, table, body string
+		if err := rows.Scan(&schema, &name, &tableSchema, &table, &body); err != nil {
+			return err
+		}
+		trigger := Node{Schema: schema, Name: name, Type: "TRIGGER"}
+		on, _ := l.resolve(tableSchema, table, "", false)
+		l.g.AddEdge(trigger, on)
+		l.addParsed(trigger, body, true, true)
+	}
+	return rows.Err()
+}
+
+func (l *loader) events(ctx context.Context, db *sql.DB, in string, args []interface{}) error {
+	rows, err := db.QueryContext(ctx, `
+        SELECT EVENT_SCHEMA, EVENT_NAME, EVENT_DEFINITION
+        FROM INFORMATION_SCHEMA.EVENTS WHERE EVENT_SCHEMA IN `+in, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schema, name, body string
+		if err := rows.Scan(&schema, &name, &body); err != nil {
+			return err
+		}
+		l.addParsed(Node{Schema: schema, Name: name, Type: "EVENT"}, body, true, true)
+	}
+	return rows.Err()
+}
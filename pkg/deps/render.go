@@ -0,0 +1,124 @@
+package deps
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// label renders a node for trees: its type, schema.name and why it has no
+// definition
+func label(n Node) string {
+	objectType := n.Type
+	if objectType == "" {
+		objectType = "TABLE?"
+	}
+	s := objectType + " " + n.String()
+	switch {
+	case n.Missing:
+		s += " (not found)"
+	case n.External:
+		s += " (not loaded)"
+	}
+	return s
+}
+
+// WriteTree writes what each root uses as an indented tree, or with reverse
+// what uses it. A node already expanded earlier is marked "(see above)"
+// instead of being expanded again, and a cycle back to a node on the current
+// path is marked "(cycle)".
+func WriteTree(w io.Writer, g *Graph, roots []Node, reverse bool) error {
+	next := g.Uses
+	if reverse {
+		next = g.UsedBy
+	}
+	expanded := make(map[string]bool)
+	onPath := make(map[string]bool)
+
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	var walk func(n Node, prefix string)
+	walk = func(n Node, prefix string) {
+		children := next(n)
+		k := key(n)
+		onPath[k] = true
+		expanded[k] = true
+		for i, child := range children {
+			branch, indent := "├── ", "│   "
+			if i == len(children)-1 {
+				branch, indent = "└── ", "    "
+			}
+			ck := key(child)
+			switch {
+			case onPath[ck]:
+				printf("%s%s%s (cycle)\n", prefix, branch, label(child))
+			case expanded[ck] && len(next(child)) > 0:
+				printf("%s%s%s (see above)\n", prefix, branch, label(child))
+			default:
+				printf("%s%s%s\n", prefix, branch, label(child))
+				walk(child, prefix+indent)
+			}
+		}
+		onPath[k] = false
+	}
+	for _, root := range roots {
+		printf("%s\n", label(root))
+		walk(root, "")
+	}
+	return err
+}
+
+// dotShapes distinguishes the types of objects in DOT output
+var dotShapes = map[string]string{
+	"TABLE":     "box",
+	"VIEW":      "ellipse",
+	"PROCEDURE": "component",
+	"FUNCTION":  "component",
+	"TRIGGER":   "hexagon",
+	"EVENT":     "octagon",
+}
+
+// WriteDOT writes the given nodes and the edges between them in Graphviz DOT
+// format, with an arrow from each object to the objects it uses
+func WriteDOT(w io.Writer, g *Graph, nodes []Node) error {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n\trankdir=LR;\n")
+	sorted := append([]Node(nil), nodes...)
+	sortNodes(sorted)
+	for _, n := range sorted {
+		shape := dotShapes[n.Type]
+		if shape == "" {
+			shape = "box"
+		}
+		style := ""
+		if !n.loaded() {
+			style = ", style=dashed"
+		}
+		text := n.String()
+		if n.Type != "" {
+			text += "\\n" + strings.ToLower(n.Type)
+		}
+		fmt.Fprintf(&b, "\t%s [label=%s, shape=%s%s];\n", dotID(n), dotString(text), shape, style)
+	}
+	for _, e := range g.Edges(nodes) {
+		fmt.Fprintf(&b, "\t%s -> %s;\n", dotID(e.From), dotID(e.To))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotID names a node uniquely, since a table and a routine may share a name
+func dotID(n Node) string {
+	return dotString(strings.ToLower(namespace(n.Type)) + ":" + n.String())
+}
+
+// dotString quotes s as a DOT string. Backslashes are left alone so that
+// escapes such as \n in labels keep working.
+func dotString(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,49 @@
+package inventory
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitDefiner splits user@host on the last @, since user names may contain one
+func SplitDefiner(definer string) (string, string) {
+	i := strings.LastIndex(definer, "@")
+	if i == -1 {
+		return definer, ""
+	}
+	return definer[:i], definer[i+1:]
+}
+
+// ParseDefiner splits a user@host spec, accepting the quoting styles MySQL does
+func ParseDefiner(spec string) (string, string, error) {
+	user, host := SplitDefiner(strings.TrimSpace(spec))
+	user = unquote(user)
+	host = unquote(host)
+	if user == "" || host == "" {
+		return "", "", fmt.Errorf("invalid definer %q, expected user@host", spec)
+	}
+	return user, host, nil
+}
+
+// DefinerMatches reports whether an INFORMATION_SCHEMA definer equals a user@host spec.
+// User names are compared exactly and host names without regard to case.
+func DefinerMatches(definer, spec string) bool {
+	user, host, err := ParseDefiner(spec)
+	if err != nil {
+		return false
+	}
+	u, h := SplitDefiner(definer)
+	return u == user && strings.EqualFold(h, host)
+}
+
+// unquote strips one level of backtick, single or double quotes
+func unquote(s string) string {
+	if len(s) >= 2 {
+		q := s[0]
+		if (q == '`' || q == '\'' || q == '"') && s[len(s)-1] == q {
+			inner := s[1 : len(s)-1]
+			return strings.ReplaceAll(inner, string([]byte{q, q}), string(q))
+		}
+	}
+	return s
+}
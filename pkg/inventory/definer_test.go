@@ -0,0 +1,55 @@
+package inventory
+
+import "testing"
+
+func TestParseDefiner(t *testing.T) {
+	tests := []struct {
+		spec     string
+		user     string
+		host     string
+		hasError bool
+	}{
+		{spec: "app@%", user: "app", host: "%"},
+		{spec: "`app`@`10.0.%`", user: "app", host: "10.0.%"},
+		{spec: "'app'@'localhost'", user: "app", host: "localhost"},
+		{spec: "a@b@localhost", user: "a@b", host: "localhost"},
+		{spec: " app@localhost ", user: "app", host: "localhost"},
+		{spec: "app", hasError: true},
+		{spec: "@localhost", hasError: true},
+	}
+	for _, tt := range tests {
+		user, host, err := ParseDefiner(tt.spec)
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("ParseDefiner(%q) = %q, %q, want error", tt.spec, user, host)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDefiner(%q) error: %v", tt.spec, err)
+			continue
+		}
+		if user != tt.user || host != tt.host {
+			t.Errorf("ParseDefiner(%q) = %q, %q, want %q, %q", tt.spec, user, host, tt.user, tt.host)
+		}
+	}
+}
+
+func TestDefinerMatches(t *testing.T) {
+	tests := []struct {
+		definer string
+		spec    string
+		want    bool
+	}{
+		{"app@localhost", "app@localhost", true},
+		{"app@LOCALHOST", "`app`@`localhost`", true},
+		{"App@localhost", "app@localhost", false},
+		{"app@%", "app@localhost", false},
+		{"app@localhost", "invalid", false},
+	}
+	for _, tt := range tests {
+		if got := DefinerMatches(tt.definer, tt.spec); got != tt.want {
+			t.Errorf("DefinerMatches(%q, %q) = %v, want %v", tt.definer, tt.spec, got, tt.want)
+		}
+	}
+}
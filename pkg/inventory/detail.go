@@ -0,0 +1,291 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// Detail holds the attributes AddDetails reads for an object. Fields that do
+// not apply to the object's type are left empty.
+type Detail struct {
+	Parameters    string `json:"parameters,omitempty"`       // e.g. IN id int, OUT total decimal(10,2)
+	Returns       string `json:"returns,omitempty"`          // return type of a function
+	Security      string `json:"security_type,omitempty"`    // DEFINER or INVOKER
+	Deterministic string `json:"is_deterministic,omitempty"` // YES or NO
+	DataAccess    string `json:"sql_data_access,omitempty"`  // CONTAINS SQL, NO SQL, READS SQL DATA or MODIFIES SQL DATA
+	SQLMode       string `json:"sql_mode,omitempty"`
+	Created       string `json:"created,omitempty"`
+	LastAltered   string `json:"last_altered,omitempty"`
+	Comment       string `json:"comment,omitempty"`
+
+	// Triggers
+	Table            string `json:"table,omitempty"`              // table the trigger belongs to
+	Timing           string `json:"timing,omitempty"`             // BEFORE or AFTER
+	Manipulation     string `json:"event_manipulation,omitempty"` // INSERT, UPDATE or DELETE
+	ActionOrder      string `json:"action_order,omitempty"`       // position among the table's triggers for the same timing and event
+	ReferencedTables string `json:"referenced_tables,omitempty"`  // tables the trigger body uses, comma-separated
+
+	// Events
+	Status       string `json:"status,omitempty"`   // ENABLED, DISABLED, SLAVESIDE_DISABLED or REPLICA_SIDE_DISABLED
+	Schedule     string `json:"schedule,omitempty"` // RECURRING, or ONE TIME AT <time>
+	Interval     string `json:"interval,omitempty"` // e.g. 1 DAY
+	Starts       string `json:"starts,omitempty"`
+	Ends         string `json:"ends,omitempty"`
+	LastExecuted string `json:"last_executed,omitempty"`
+	OnCompletion string `json:"on_completion,omitempty"` // PRESERVE or NOT PRESERVE
+
+	// Tables
+	Engine        string `json:"engine,omitempty"`
+	RowFormat     string `json:"row_format,omitempty"`
+	Rows          string `json:"table_rows,omitempty"`     // exact for MyISAM, an estimate for InnoDB
+	DataLength    string `json:"data_length,omitempty"`    // bytes
+	IndexLength   string `json:"index_length,omitempty"`   // bytes
+	AutoIncrement string `json:"auto_increment,omitempty"` // next AUTO_INCREMENT value
+	Collation     string `json:"table_collation,omitempty"`
+}
+
+// objectKey identifies an object of a schema. Procedures and functions have
+// separate namespaces, so the type is part of the key.
+func objectKey(objectType, name string) string {
+	return objectType + "\x00" + name
+}
+
+// AddDetails fills in the Detail of the objects of schema: the signature and
+// characteristics of routines, the SQL SECURITY of views, the table, timing
+// and body references of triggers, the status and schedule of events, and the
+// engine, size and collation of tables. Objects of other schemas are left alone.
+func AddDetails(ctx context.Context, db *sql.DB, schema string, objects []Object) error {
+	index := make(map[string]*Object)
+	hasTables := false
+	for i := range objects {
+		if objects[i].Schema == schema {
+			index[objectKey(objects[i].Type, objects[i].Name)] = &objects[i]
+			hasTables = hasTables || objects[i].Type == "TABLE"
+		}
+	}
+	if len(index) == 0 {
+		return nil
+	}
+
+	if err := addRoutineDetails(ctx, db, schema, index); err != nil {
+		return fmt.Errorf("routines: %v", err)
+	}
+	if err := addParameters(ctx, db, schema, index); err != nil {
+		return fmt.Errorf("parameters: %v", err)
+	}
+	if err := addViewDetails(ctx, db, schema, index); err != nil {
+		return fmt.Errorf("views: %v", err)
+	}
+	if err := addTriggerDetails(ctx, db, schema, index); err != nil {
+		return fmt.Errorf("triggers: %v", err)
+	}
+	if err := addEventDetails(ctx, db, schema, index); err != nil {
+		return fmt.Errorf("events: %v", err)
+	}
+	// Table statistics can be slow to read, so only ask when tables were listed
+	if hasTables {
+		if err := addTableDetails(ctx, db, schema, index); err != nil {
+			return fmt.Errorf("tables: %v", err)
+		}
+	}
+	return nil
+}
+
+func addRoutineDetails(ctx context.Context, db *sql.DB, schema string, index map[string]*Object) error {
+	rows, err := db.QueryContext(ctx, `
+        SELECT ROUTINE_NAME, ROUTINE_TYPE, DTD_IDENTIFIER, SECURITY_TYPE, IS_DETERMINISTIC,
+               SQL_DATA_ACCESS, SQL_MODE, CREATED, LAST_ALTERED, ROUTINE_COMMENT
+        FROM INFORMATION_SCHEMA.ROUTINES WHERE ROUTINE_SCHEMA = ?`, schema)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, routineType string
+		var returns, created, altered sql.NullString
+		var d Detail
+		err := rows.Scan(&name, &routineType, &returns, &d.Security, &d.Deterministic,
+			&d.DataAccess, &d.SQLMode, &created, &altered, &d.Comment)
+		if err != nil {
+			return err
+		}
+		object := index[objectKey(routineType, name)]
+		if object == nil {
+			continue
+		}
+		d.Returns = returns.String
+		d.Created = created.String
+		d.LastAltered = altered.String
+		object.Detail = d
+	}
+	return rows.Err()
+}
+
+func addParameters(ctx context.Context, db *sql.DB, schema string, index map[string]*Object) error {
+	// Position 0 is the return value of a function, which ROUTINES already has
+	rows, err := db.QueryContext(ctx, `
+        SELECT SPECIFIC_NAME, ROUTINE_TYPE, PARAMETER_MODE, PARAMETER_NAME, DTD_IDENTIFIER
+        FROM INFORMATION_SCHEMA.PARAMETERS
+        WHERE SPECIFIC_SCHEMA = ? AND ORDINAL_POSITION > 0
+        ORDER BY SPECIFIC_NAME, ROUTINE_TYPE, ORDINAL_POSITION`, schema)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	params := make(map[string][]string)
+	for rows.Next() {
+		var name, routineType, dataType string
+		var mode, paramName sql.NullString
+		if err := rows.Scan(&name, &routineType, &mode, &paramName, &dataType); err != nil {
+			return err
+		}
+		// Function parameters have no mode
+		param := strings.TrimSpace(mode.String + " " + paramName.String + " " + dataType)
+		key := objectKey(routineType, name)
+		params[key] = append(params[key], param)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for key, list := range params {
+		if object := index[key]; object != nil {
+			object.Parameters = strings.Join(list, ", ")
+		}
+	}
+	return nil
+}
+
+func addViewDetails(ctx context.Context, db *sql.DB, schema string, index map[string]*Object) error {
+	rows, err := db.QueryContext(ctx, "SELECT TABLE_NAME, SECURITY_TYPE FROM INFORMATION_SCHEMA.VIEWS WHERE TABLE_SCHEMA = ?", schema)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, security string
+		if err := rows.Scan(&name, &security); err != nil {
+			return err
+		}
+		if object := index[objectKey("VIEW", name)]; object != nil {
+			object.Security = security
+		}
+	}
+	return rows.Err()
+}
+
+func addTriggerDetails(ctx context.Context, db *sql.DB, schema string, index map[string]*Object) error {
+	query := `
+        SELECT TRIGGER_NAME, EVENT_OBJECT_TABLE, ACTION_TIMING, EVENT_MANIPULATION, %s,
+               ACTION_STATEMENT, SQL_MODE, CREATED
+        FROM INFORMATION_SCHEMA.TRIGGERS WHERE TRIGGER_SCHEMA = ?`
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(query, "ACTION_ORDER"), schema)
+	if err != nil {
+		// ACTION_ORDER only exists from 5.7.2 onwards, where a table can have
+		// several triggers for the same timing and event
+		rows, err = db.QueryContext(ctx, fmt.Sprintf(query, "1"), schema)
+		if err != nil {
+			return err
+		}
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, body string
+		var created sql.NullString
+		var d Detail
+		err := rows.Scan(&name, &d.Table, &d.Timing, &d.Manipulation, &d.ActionOrder, &body, &d.SQLMode, &created)
+		if err != nil {
+			return err
+		}
+		object := index[objectKey("TRIGGER", name)]
+		if object == nil {
+			continue
+		}
+		d.Created = created.String
+		// A body the tokenizer cannot read still gets the rest of its details
+		if tables, err := sqlparse.ReferencedTables(body); err == nil {
+			d.ReferencedTables = strings.Join(tables, ", ")
+		}
+		object.Detail = d
+	}
+	return rows.Err()
+}
+
+func addEventDetails(ctx context.Context, db *sql.DB, schema string, index map[string]*Object) error {
+	rows, err := db.QueryContext(ctx, `
+        SELECT EVENT_NAME, STATUS, EVENT_TYPE, EXECUTE_AT, INTERVAL_VALUE, INTERVAL_FIELD,
+               STARTS, ENDS, LAST_EXECUTED, ON_COMPLETION, SQL_MODE, CREATED, LAST_ALTERED, EVENT_COMMENT
+        FROM INFORMATION_SCHEMA.EVENTS WHERE EVENT_SCHEMA = ?`, schema)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, eventType string
+		var executeAt, intervalValue, intervalField, starts, ends, lastExecuted, created, altered sql.NullString
+		var d Detail
+		err := rows.Scan(&name, &d.Status, &eventType, &executeAt, &intervalValue, &intervalField,
+			&starts, &ends, &lastExecuted, &d.OnCompletion, &d.SQLMode, &created, &altered, &d.Comment)
+		if err != nil {
+			return err
+		}
+		object := index[objectKey("EVENT", name)]
+		if object == nil {
+			continue
+		}
+		d.Schedule = eventType
+		if executeAt.Valid {
+			d.Schedule += " AT " + executeAt.String
+		}
+		if intervalValue.Valid {
+			d.Interval = intervalValue.String + " " + intervalField.String
+		}
+		d.Starts = starts.String
+		d.Ends = ends.String
+		d.LastExecuted = lastExecuted.String
+		d.Created = created.String
+		d.LastAltered = altered.String
+		object.Detail = d
+	}
+	return rows.Err()
+}
+
+func addTableDetails(ctx context.Context, db *sql.DB, schema string, index map[string]*Object) error {
+	rows, err := db.QueryContext(ctx, `
+        SELECT TABLE_NAME, ENGINE, ROW_FORMAT, TABLE_ROWS, DATA_LENGTH, INDEX_LENGTH,
+               AUTO_INCREMENT, TABLE_COLLATION, CREATE_TIME, TABLE_COMMENT
+        FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'`, schema)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var engine, rowFormat, tableRows, dataLength, indexLength, autoIncrement, collation, created, comment sql.NullString
+		err := rows.Scan(&name, &engine, &rowFormat, &tableRows, &dataLength, &indexLength,
+			&autoIncrement, &collation, &created, &comment)
+		if err != nil {
+			return err
+		}
+		object := index[objectKey("TABLE", name)]
+		if object == nil {
+			continue
+		}
+		object.Detail = Detail{
+			Engine:        engine.String,
+			RowFormat:     rowFormat.String,
+			Rows:          tableRows.String,
+			DataLength:    dataLength.String,
+			IndexLength:   indexLength.String,
+			AutoIncrement: autoIncrement.String,
+			Collation:     collation.String,
+			Created:       created.String,
+			Comment:       comment.String,
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,62 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Event statuses as INFORMATION_SCHEMA.EVENTS reports them. Newer servers
+// report REPLICA_SIDE_DISABLED where older ones report SLAVESIDE_DISABLED.
+const (
+	EventEnabled             = "ENABLED"
+	EventDisabled            = "DISABLED"
+	EventSlavesideDisabled   = "SLAVESIDE_DISABLED"
+	EventReplicaSideDisabled = "REPLICA_SIDE_DISABLED"
+)
+
+// ReplicaSideDisabled reports whether an event status is SLAVESIDE_DISABLED
+// or REPLICA_SIDE_DISABLED: the event was created on another server and
+// replicated here disabled
+func ReplicaSideDisabled(status string) bool {
+	return status == EventSlavesideDisabled || status == EventReplicaSideDisabled
+}
+
+// EventWarnings checks the events among objects, whose details must have
+// been read with AddDetails, against the server's event scheduler. Enabled
+// events do not run while the scheduler is OFF, and replica-side disabled
+// events were created on another server and do not run here until they are
+// enabled, which is easy to miss after a failover.
+func EventWarnings(ctx context.Context, db *sql.DB, objects []Object) ([]string, error) {
+	var enabled []string
+	var replicaSide []Object
+	for _, o := range objects {
+		if o.Type != "EVENT" {
+			continue
+		}
+		switch {
+		case o.Status == EventEnabled:
+			enabled = append(enabled, o.Schema+"."+o.Name)
+		case ReplicaSideDisabled(o.Status):
+			replicaSide = append(replicaSide, o)
+		}
+	}
+
+	var warnings []string
+	if len(enabled) > 0 {
+		var scheduler string
+		if err := db.QueryRowContext(ctx, "SELECT @@GLOBAL.event_scheduler").Scan(&scheduler); err != nil {
+			return nil, fmt.Errorf("error reading event_scheduler: %v", err)
+		}
+		if !strings.EqualFold(scheduler, "ON") {
+			warnings = append(warnings, fmt.Sprintf("event_scheduler is %s, so %d enabled events do not run: %s",
+				strings.ToUpper(scheduler), len(enabled), strings.Join(enabled, ", ")))
+		}
+	}
+	for _, o := range replicaSide {
+		warnings = append(warnings, fmt.Sprintf("event %s.%s is %s and does not run on this server; "+
+			"if it is now the primary, enable it with ALTER EVENT ... ENABLE", o.Schema, o.Name, o.Status))
+	}
+	return warnings, nil
+}
@@ -0,0 +1,27 @@
+package inventory
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestEventWarningsReplicaSideDisabled(t *testing.T) {
+	event := func(name, status string) Object {
+		return Object{Schema: "app", Name: name, Type: "EVENT", Detail: Detail{Status: status}}
+	}
+	objects := []Object{
+		event("old_purge", "SLAVESIDE_DISABLED"),
+		event("new_purge", "REPLICA_SIDE_DISABLED"),
+		event("off", "DISABLED"),
+	}
+	// Without enabled events the scheduler is not read, so no server is needed
+	warnings, err := EventWarnings(context.Background(), nil, objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 2 || !strings.HasPrefix(warnings[0], "event app.old_purge is SLAVESIDE_DISABLED ") ||
+		!strings.HasPrefix(warnings[1], "event app.new_purge is REPLICA_SIDE_DISABLED ") {
+		t.Errorf("EventWarnings() = %q", warnings)
+	}
+}
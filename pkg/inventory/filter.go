@@ -0,0 +1,253 @@
+package inventory
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ObjectTypes are the types Filter accepts, in the order ObjectsMatching lists them
+var ObjectTypes = []string{"PROCEDURE", "FUNCTION", "VIEW", "TRIGGER", "EVENT", "TABLE"}
+
+// Tables far outnumber stored programs in most schemas, so a filter without
+// types leaves them out
+func defaultType(t string) bool {
+	return t != "TABLE"
+}
+
+// Pattern matches object names. It is either a list of glob patterns, as
+// path.Match understands them, or a single regular expression.
+type Pattern struct {
+	globs []string
+	re    *regexp.Regexp
+}
+
+// ParsePattern parses a comma-separated list of globs such as rpt_*,tmp_?,
+// or a regular expression between slashes such as /^rpt_[0-9]+$/
+func ParsePattern(spec string) (*Pattern, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	if len(spec) >= 2 && strings.HasPrefix(spec, "/") && strings.HasSuffix(spec, "/") {
+		re, err := regexp.Compile(spec[1 : len(spec)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid name pattern %s: %v", spec, err)
+		}
+		return &Pattern{re: re}, nil
+	}
+	p := &Pattern{}
+	for _, glob := range strings.Split(spec, ",") {
+		if glob = strings.TrimSpace(glob); glob == "" {
+			continue
+		}
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %s: %v", glob, err)
+		}
+		p.globs = append(p.globs, glob)
+	}
+	if len(p.globs) == 0 {
+		return nil, fmt.Errorf("invalid name pattern %q: no names or globs", spec)
+	}
+	return p, nil
+}
+
+// Match reports whether name matches the pattern. Names are compared with
+// regard to case, as MySQL does on case-sensitive file systems.
+func (p *Pattern) Match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	for _, glob := range p.globs {
+		if matched, _ := path.Match(glob, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// condition returns a SQL condition on column that holds for at least the
+// names the pattern matches, or "" if the pattern cannot be expressed in SQL.
+// Regular expressions are only checked in Go: MySQL's REGEXP has its own
+// syntax, which rejects or reads differently much of what Go accepts, such
+// as (?i), \pL and non-greedy repetition. Callers must still check every row
+// with Match.
+func (p *Pattern) condition(column string) (string, []interface{}) {
+	if p.re != nil || len(p.globs) == 0 {
+		return "", nil
+	}
+	var conds []string
+	var args []interface{}
+	for _, glob := range p.globs {
+		like, ok := globToLike(glob)
+		if !ok {
+			return "", nil
+		}
+		conds = append(conds, column+" LIKE BINARY ?")
+		args = append(args, like)
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+// exclusion returns a SQL condition on column that rejects only names the
+// pattern matches, or "" if that cannot be expressed in SQL. Only lists of
+// plain names qualify: LIKE's % also matches the / that * stops at.
+func (p *Pattern) exclusion(column string) (string, []interface{}) {
+	for _, glob := range p.globs {
+		if strings.ContainsAny(glob, "*?") {
+			return "", nil
+		}
+	}
+	cond, args := p.condition(column)
+	if cond == "" {
+		return "", nil
+	}
+	return "NOT " + cond, args
+}
+
+// globToLike converts a glob to a LIKE pattern. Character classes have no
+// LIKE equivalent, so globs with them are reported as not convertible.
+func globToLike(glob string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		case '[':
+			return "", false
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				c = glob[i]
+			}
+			fallthrough
+		default:
+			if c == '%' || c == '_' || c == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
+
+// Filter narrows the objects ObjectsMatching returns. Zero values match
+// everything except tables, which are only listed when Types has TABLE.
+type Filter struct {
+	Types   []string // object types, e.g. PROCEDURE and VIEW
+	Names   *Pattern // only names matching this
+	Exclude *Pattern // no names matching this
+	Definer string   // user@host
+}
+
+// ParseFilter builds a filter from the command line form of its parts:
+// comma-separated types, name patterns as ParsePattern reads them, and a
+// user@host definer
+func ParseFilter(types, names, exclude, definer string) (Filter, error) {
+	var f Filter
+	for _, t := range strings.Split(types, ",") {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if !f.knownType(t) {
+			return Filter{}, fmt.Errorf("invalid object type: %s. Must be one of %s", t, strings.Join(ObjectTypes, ", "))
+		}
+		f.Types = append(f.Types, t)
+	}
+	var err error
+	if f.Names, err = ParsePattern(names); err != nil {
+		return Filter{}, err
+	}
+	if f.Exclude, err = ParsePattern(exclude); err != nil {
+		return Filter{}, err
+	}
+	if definer != "" {
+		if _, _, err := ParseDefiner(definer); err != nil {
+			return Filter{}, err
+		}
+		f.Definer = definer
+	}
+	return f, nil
+}
+
+func (f Filter) knownType(t string) bool {
+	for _, known := range ObjectTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// HasType reports whether objects of type t pass the filter
+func (f Filter) HasType(t string) bool {
+	if len(f.Types) == 0 {
+		return defaultType(t)
+	}
+	for _, want := range f.Types {
+		if want == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WithType returns a copy of the filter that also selects objects of type t
+func (f Filter) WithType(t string) Filter {
+	var types []string
+	for _, known := range ObjectTypes {
+		if f.HasType(known) || known == t {
+			types = append(types, known)
+		}
+	}
+	f.Types = types
+	return f
+}
+
+// Match reports whether an object passes the filter
+func (f Filter) Match(o Object) bool {
+	switch {
+	case !f.HasType(o.Type):
+		return false
+	case !f.MatchName(o.Name):
+		return false
+	case f.Definer != "" && !DefinerMatches(o.Definer, f.Definer):
+		return false
+	}
+	return true
+}
+
+// MatchName reports whether name passes the filter's name patterns
+func (f Filter) MatchName(name string) bool {
+	return (f.Names == nil || f.Names.Match(name)) && (f.Exclude == nil || !f.Exclude.Match(name))
+}
+
+// conditions returns the SQL conditions that push the filter's names and
+// definer down into a query on nameColumn and DEFINER
+func (f Filter) conditions(nameColumn string) (string, []interface{}) {
+	var sql string
+	var args []interface{}
+	add := func(cond string, condArgs []interface{}) {
+		if cond != "" {
+			sql += " AND " + cond
+			args = append(args, condArgs...)
+		}
+	}
+	if f.Names != nil {
+		add(f.Names.condition(nameColumn))
+	}
+	if f.Exclude != nil {
+		add(f.Exclude.exclusion(nameColumn))
+	}
+	if f.Definer != "" {
+		// DefinerMatches already accepted the spec, so this cannot fail
+		user, host, _ := ParseDefiner(f.Definer)
+		add("DEFINER = ?", []interface{}{user + "@" + host})
+	}
+	return sql, args
+}
@@ -0,0 +1,136 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		types, names, exclude, definer string
+		object                         Object
+		want                           bool
+	}{
+		{object: Object{Name: "anything", Type: "VIEW"}, want: true},
+		{types: "procedure,function", object: Object{Name: "p", Type: "FUNCTION"}, want: true},
+		{types: "PROCEDURE", object: Object{Name: "v", Type: "VIEW"}, want: false},
+		{object: Object{Name: "t", Type: "TABLE"}, want: false},
+		{types: "table", object: Object{Name: "t", Type: "TABLE"}, want: true},
+		{names: "rpt_*,tmp_?", object: Object{Name: "tmp_1", Type: "VIEW"}, want: true},
+		{names: "rpt_*", object: Object{Name: "RPT_daily", Type: "VIEW"}, want: false},
+		{names: "/^get_[a-z]+$/", object: Object{Name: "get_x", Type: "FUNCTION"}, want: true},
+		{names: "/^get_[a-z]+$/", object: Object{Name: "get_1", Type: "FUNCTION"}, want: false},
+		{names: "rpt_*", exclude: "*_old", object: Object{Name: "rpt_old", Type: "VIEW"}, want: false},
+		{definer: "`app`@`%`", object: Object{Name: "v", Type: "VIEW", Definer: "app@%"}, want: true},
+		{definer: "app@%", object: Object{Name: "v", Type: "VIEW", Definer: "root@localhost"}, want: false},
+	}
+	for _, tt := range tests {
+		filter, err := ParseFilter(tt.types, tt.names, tt.exclude, tt.definer)
+		if err != nil {
+			t.Errorf("ParseFilter(%q, %q, %q, %q) error: %v", tt.types, tt.names, tt.exclude, tt.definer, err)
+			continue
+		}
+		if got := filter.Match(tt.object); got != tt.want {
+			t.Errorf("filter %q/%q/%q/%q Match(%s %s) = %v, want %v", tt.types, tt.names, tt.exclude, tt.definer, tt.object.Type, tt.object.Name, got, tt.want)
+		}
+	}
+
+	for _, bad := range [][4]string{{"TABLESPACE", "", "", ""}, {"", "[", "", ""}, {"", "/(/", "", ""}, {"", ",", "", ""}, {"", "", " , ", ""}, {"", "", "", "nohost"}} {
+		if _, err := ParseFilter(bad[0], bad[1], bad[2], bad[3]); err == nil {
+			t.Errorf("ParseFilter(%q) accepted an invalid filter", bad)
+		}
+	}
+}
+
+func TestFilterWithType(t *testing.T) {
+	tests := []struct {
+		types string
+		want  string
+	}{
+		{"", "PROCEDURE FUNCTION VIEW TRIGGER EVENT TABLE"},
+		{"view", "VIEW TABLE"},
+		{"table", "TABLE"},
+	}
+	for _, tt := range tests {
+		filter, err := ParseFilter(tt.types, "", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(filter.WithType("TABLE").Types, " "); got != tt.want {
+			t.Errorf("ParseFilter(%q).WithType(TABLE) types = %s, want %s", tt.types, got, tt.want)
+		}
+	}
+}
+
+func TestFilterConditions(t *testing.T) {
+	filter, err := ParseFilter("", `rpt_*,a\_b?`, "tmp[0-9]", "app@%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql, args := filter.conditions("TABLE_NAME")
+	// Character classes have no LIKE equivalent, so that exclusion is checked in Go only
+	want := " AND (TABLE_NAME LIKE BINARY ? OR TABLE_NAME LIKE BINARY ?) AND DEFINER = ?"
+	if sql != want {
+		t.Errorf("conditions() = %q, want %q", sql, want)
+	}
+	wantArgs := []interface{}{`rpt\_%`, `a\_b_`, "app@%"}
+	if len(args) != len(wantArgs) {
+		t.Fatalf("conditions() args = %v, want %v", args, wantArgs)
+	}
+	for i := range args {
+		if args[i] != wantArgs[i] {
+			t.Errorf("conditions() args = %v, want %v", args, wantArgs)
+			break
+		}
+	}
+}
+
+func TestFilterRegexpNotPushedDown(t *testing.T) {
+	// Go syntax that MySQL's REGEXP rejects or reads differently
+	for _, names := range []string{`/(?i)^rpt_\d+$/`, `/^\pL+_v\d$/`, `/^tmp_.*?_old$/`} {
+		filter, err := ParseFilter("", names, names, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sql, args := filter.conditions("TABLE_NAME"); sql != "" || len(args) != 0 {
+			t.Errorf("ParseFilter(%q).conditions() = %q %v, want none", names, sql, args)
+		}
+	}
+
+	filter, err := ParseFilter("", `/(?i)^rpt_\d+$/`, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"RPT_2024": true, "rpt_1": true, "rpt_x": false} {
+		if got := filter.MatchName(name); got != want {
+			t.Errorf("MatchName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestEmptyPatternCondition(t *testing.T) {
+	if sql, args := (&Pattern{}).condition("TABLE_NAME"); sql != "" || args != nil {
+		t.Errorf("condition() of an empty pattern = %q %v, want none", sql, args)
+	}
+}
+
+func TestFilterExclusion(t *testing.T) {
+	tests := []struct {
+		exclude string
+		want    string
+	}{
+		{"legacy,old_report", " AND NOT (TABLE_NAME LIKE BINARY ? OR TABLE_NAME LIKE BINARY ?)"},
+		// LIKE's % would also reject a/b_old, which *_old does not match
+		{"*_old", ""},
+		{"tmp_?", ""},
+	}
+	for _, tt := range tests {
+		filter, err := ParseFilter("", "", tt.exclude, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sql, _ := filter.conditions("TABLE_NAME"); sql != tt.want {
+			t.Errorf("ParseFilter(exclude %q).conditions() = %q, want %q", tt.exclude, sql, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package inventory lists the stored programs, views and tables of MySQL
+// schemas together with their definers.
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// Object is a routine, view, trigger, event or table
+type Object struct {
+	Host    string `json:"host"`
+	Schema  string `json:"schema"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`    // PROCEDURE, FUNCTION, VIEW, TRIGGER, EVENT or TABLE
+	Definer string `json:"definer"` // empty for tables
+	Detail         // only filled in by AddDetails
+}
+
+// Schemas skipped when scanning all databases unless asked for
+var systemSchemas = map[string]bool{
+	"mysql":              true,
+	"sys":                true,
+	"information_schema": true,
+	"performance_schema": true,
+}
+
+// IsSystemSchema reports whether schema is one of MySQL's own schemas
+func IsSystemSchema(schema string) bool {
+	return systemSchemas[strings.ToLower(schema)]
+}
+
+// Databases returns the names of all schemas on the server
+func Databases(ctx context.Context, db *sql.DB) ([]string, error) {
+	var databases []string
+	rows, err := db.QueryContext(ctx, "SELECT schema_name FROM information_schema.schemata")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var database string
+		if err := rows.Scan(&database); err != nil {
+			return nil, err
+		}
+		databases = append(databases, database)
+	}
+	return databases, rows.Err()
+}
+
+// Objects returns all procedures, functions, views, triggers and events in
+// schema. Host is left empty for the caller to fill in.
+func Objects(ctx context.Context, db *sql.DB, schema string) ([]Object, error) {
+	return ObjectsMatching(ctx, db, schema, Filter{})
+}
+
+// Sources of the objects of each type: a query of the INFORMATION_SCHEMA
+// table, ending in a WHERE clause on the schema, and the table's name column
+var objectSources = []struct {
+	types   []string
+	query   string
+	name    string
+	definer bool // whether the table has a DEFINER column
+}{
+	{[]string{"PROCEDURE", "FUNCTION"}, "SELECT ROUTINE_NAME, ROUTINE_TYPE, DEFINER FROM INFORMATION_SCHEMA.ROUTINES WHERE ROUTINE_SCHEMA = ?", "ROUTINE_NAME", true},
+	{[]string{"VIEW"}, "SELECT TABLE_NAME, 'VIEW', DEFINER FROM INFORMATION_SCHEMA.VIEWS WHERE TABLE_SCHEMA = ?", "TABLE_NAME", true},
+	{[]string{"TRIGGER"}, "SELECT TRIGGER_NAME, 'TRIGGER', DEFINER FROM INFORMATION_SCHEMA.TRIGGERS WHERE TRIGGER_SCHEMA = ?", "TRIGGER_NAME", true},
+	{[]string{"EVENT"}, "SELECT EVENT_NAME, 'EVENT', DEFINER FROM INFORMATION_SCHEMA.EVENTS WHERE EVENT_SCHEMA = ?", "EVENT_NAME", true},
+	{[]string{"TABLE"}, "SELECT TABLE_NAME, 'TABLE', '' FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'", "TABLE_NAME", false},
+}
+
+// ObjectsMatching returns the objects of schema that pass filter. The filter
+// is pushed down into the INFORMATION_SCHEMA queries as far as SQL can
+// express it, so that types that are not wanted are not read at all.
+func ObjectsMatching(ctx context.Context, db *sql.DB, schema string, filter Filter) ([]Object, error) {
+	var branches []string
+	var args []interface{}
+	for _, source := range objectSources {
+		var types []string
+		for _, t := range source.types {
+			if filter.HasType(t) {
+				types = append(types, t)
+			}
+		}
+		// Objects without a definer never match a filter on one
+		if len(types) == 0 || (filter.Definer != "" && !source.definer) {
+			continue
+		}
+		branch := source.query
+		args = append(args, schema)
+		// Only routines share a table between types
+		if len(types) < len(source.types) {
+			branch += " AND ROUTINE_TYPE = ?"
+			args = append(args, types[0])
+		}
+		conds, condArgs := filter.conditions(source.name)
+		branch += conds
+		args = append(args, condArgs...)
+		branches = append(branches, branch)
+	}
+	if len(branches) == 0 {
+		return nil, nil
+	}
+
+	rows, err := db.QueryContext(ctx, strings.Join(branches, "\nUNION ALL\n"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []Object
+	for rows.Next() {
+		o := Object{Schema: schema}
+		if err := rows.Scan(&o.Name, &o.Type, &o.Definer); err != nil {
+			return nil, err
+		}
+		// SQL compares names without regard to case, so check again exactly
+		if filter.Match(o) {
+			objects = append(objects, o)
+		}
+	}
+	return objects, rows.Err()
+}
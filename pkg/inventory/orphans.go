@@ -0,0 +1,145 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Definer statuses reported by FindOrphans. MISSING and HOST_MISMATCH are
+// orphans that break the object; LOCKED and EXPIRED are warnings because
+// MySQL still runs objects whose definer cannot log in.
+const (
+	DefinerMissing      = "MISSING"
+	DefinerHostMismatch = "HOST_MISMATCH"
+	DefinerLocked       = "LOCKED"
+	DefinerExpired      = "EXPIRED"
+)
+
+// Account is a row of mysql.user
+type Account struct {
+	User    string
+	Host    string
+	Locked  bool
+	Expired bool
+	Role    bool
+}
+
+// Accounts maps user names to all of their accounts
+type Accounts map[string][]Account
+
+// Finding is an object whose definer has a problem
+type Finding struct {
+	Object
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// IsOrphan reports whether the finding breaks the object rather than being a warning
+func (f Finding) IsOrphan() bool {
+	return f.Status == DefinerMissing || f.Status == DefinerHostMismatch
+}
+
+// LoadAccounts reads mysql.user, and mysql.role_edges on servers with roles
+func LoadAccounts(ctx context.Context, db *sql.DB) (Accounts, error) {
+	rows, err := db.QueryContext(ctx, "SELECT User, Host, account_locked, password_expired FROM mysql.user")
+	if err != nil {
+		// account_locked only exists from 5.7.6 onwards
+		rows, err = db.QueryContext(ctx, "SELECT User, Host, 'N', password_expired FROM mysql.user")
+		if err != nil {
+			return nil, fmt.Errorf("error reading mysql.user (SELECT privilege required): %v", err)
+		}
+	}
+	defer rows.Close()
+
+	accounts := make(Accounts)
+	for rows.Next() {
+		var a Account
+		var locked, expired string
+		if err := rows.Scan(&a.User, &a.Host, &locked, &expired); err != nil {
+			return nil, err
+		}
+		a.Locked = strings.EqualFold(locked, "Y")
+		a.Expired = strings.EqualFold(expired, "Y")
+		accounts[a.User] = append(accounts[a.User], a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Roles are locked accounts by design, so they must not be reported as such.
+	// mysql.role_edges does not exist before 8.0, which simply means no roles.
+	roleRows, err := db.QueryContext(ctx, "SELECT DISTINCT FROM_USER, FROM_HOST FROM mysql.role_edges")
+	if err != nil {
+		return accounts, nil
+	}
+	defer roleRows.Close()
+	for roleRows.Next() {
+		var user, host string
+		if err := roleRows.Scan(&user, &host); err != nil {
+			return nil, err
+		}
+		for i, a := range accounts[user] {
+			if strings.EqualFold(a.Host, host) {
+				accounts[user][i].Role = true
+			}
+		}
+	}
+	return accounts, roleRows.Err()
+}
+
+// CheckDefiner returns the status of a definer, or an empty status if it is fine
+func (accounts Accounts) CheckDefiner(definer string) (string, string) {
+	user, host := SplitDefiner(definer)
+	candidates := accounts[user]
+	if len(candidates) == 0 {
+		return DefinerMissing, fmt.Sprintf("no account named '%s'", user)
+	}
+
+	// The definer host is stored literally, so it must match an account exactly
+	for _, a := range candidates {
+		if !strings.EqualFold(a.Host, host) {
+			continue
+		}
+		switch {
+		case a.Role:
+			return "", ""
+		case a.Locked:
+			return DefinerLocked, "account is locked"
+		case a.Expired:
+			return DefinerExpired, "password is expired"
+		}
+		return "", ""
+	}
+
+	var existing []string
+	for _, a := range candidates {
+		existing = append(existing, a.User+"@"+a.Host)
+	}
+	sort.Strings(existing)
+	return DefinerHostMismatch, "exists only as " + strings.Join(existing, ", ")
+}
+
+// FindOrphans checks the definer of every object against mysql.user
+func FindOrphans(ctx context.Context, db *sql.DB, objects []Object) ([]Finding, error) {
+	accounts, err := LoadAccounts(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, object := range objects {
+		// Tables have no definer to check
+		if object.Definer == "" {
+			continue
+		}
+		status, detail := accounts.CheckDefiner(object.Definer)
+		if status == "" {
+			continue
+		}
+		findings = append(findings, Finding{Object: object, Status: status, Detail: detail})
+	}
+	return findings, nil
+}
@@ -0,0 +1,154 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/ChaosHour/go-pvt/pkg/connect"
+)
+
+// ScanOptions controls what ScanHosts collects
+type ScanOptions struct {
+	Database      string // schema to scan, ignored with AllDatabases
+	AllDatabases  bool
+	IncludeSystem bool // with AllDatabases, also scan mysql, sys and the schema schemas
+	Orphans       bool // also check definers against mysql.user
+	Detail        bool // also read routine signatures and characteristics, see AddDetails
+	Workers       int  // hosts scanned concurrently, at least 1
+
+	// Filter selects the objects to scan
+	Filter Filter
+
+	// Connected, if set, is called after each successful connection
+	Connected func(host, hostname string)
+	// Logger, if set, receives a debug message for every schema scanned
+	Logger *slog.Logger
+	// Visit, if set, is called with the objects of every schema while the
+	// connection is still open, e.g. to read their definitions. An error
+	// stops the scan of the host.
+	Visit func(ctx context.Context, db *sql.DB, host, schema string, objects []Object) error
+}
+
+// discardLogger is used when ScanOptions has no logger
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// HostResult is the inventory of one host, or the error that stopped it
+type HostResult struct {
+	Host     string
+	Objects  []Object
+	Findings []Finding
+	Warnings []string // problems found with Detail, such as events that do not run
+	Err      error
+}
+
+// ScanHosts collects the inventory of every host using a bounded worker pool.
+// Results keep the order of hosts so the output is stable between runs.
+func ScanHosts(ctx context.Context, cfg connect.Config, hosts []string, opts ScanOptions) []HostResult {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]HostResult, len(hosts))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(hosts); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = ScanHost(ctx, cfg, hosts[i], opts)
+			}
+		}()
+	}
+	for i := range hosts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// ScanHost returns the objects of one host. With Orphans set the definers
+// are also checked against the host's accounts.
+func ScanHost(ctx context.Context, cfg connect.Config, host string, opts ScanOptions) HostResult {
+	result := HostResult{Host: host}
+	logger := opts.Logger
+	if logger == nil {
+		logger = discardLogger
+	}
+	db, err := connect.Open(ctx, cfg, host, opts.Database)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer db.Close()
+
+	if opts.Connected != nil {
+		hostname, err := connect.Hostname(ctx, db)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		opts.Connected(host, hostname)
+	}
+
+	schemas := []string{opts.Database}
+	if opts.AllDatabases {
+		databases, err := Databases(ctx, db)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		schemas = schemas[:0]
+		for _, schema := range databases {
+			if IsSystemSchema(schema) && !opts.IncludeSystem {
+				continue
+			}
+			schemas = append(schemas, schema)
+		}
+	}
+
+	for _, schema := range schemas {
+		objects, err := ObjectsMatching(ctx, db, schema, opts.Filter)
+		if err != nil {
+			result.Err = fmt.Errorf("error reading objects in %s: %v", schema, err)
+			return result
+		}
+		if opts.Detail {
+			if err := AddDetails(ctx, db, schema, objects); err != nil {
+				result.Err = fmt.Errorf("error reading details in %s: %v", schema, err)
+				return result
+			}
+		}
+		logger.Debug("scanned schema", "host", host, "schema", schema, "objects", len(objects))
+		for i := range objects {
+			objects[i].Host = host
+		}
+		if opts.Visit != nil {
+			if err := opts.Visit(ctx, db, host, schema, objects); err != nil {
+				result.Err = err
+				return result
+			}
+		}
+		result.Objects = append(result.Objects, objects...)
+	}
+
+	if opts.Detail {
+		result.Warnings, err = EventWarnings(ctx, db, result.Objects)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	if opts.Orphans {
+		result.Findings, result.Err = FindOrphans(ctx, db, result.Objects)
+	}
+	return result
+}
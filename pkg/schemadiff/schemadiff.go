@@ -0,0 +1,262 @@
+// Package schemadiff compares the stored programs, views and tables of two
+// schemas, which may be on different servers, and renders the differences of
+// their definitions as unified diffs. Snapshots keep a schema's objects as a
+// baseline to detect drift against later.
+package schemadiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/ddl"
+	"github.com/ChaosHour/go-pvt/pkg/inventory"
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// Statuses of a Difference
+const (
+	Missing        = "MISSING"         // only in the source
+	Extra          = "EXTRA"           // only in the target
+	Changed        = "CHANGED"         // the definitions differ
+	DefinerChanged = "DEFINER_CHANGED" // only the definer differs
+)
+
+// Object is an object of a schema together with its definition
+type Object struct {
+	Schema          string `json:"schema"`
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Definer         string `json:"definer"`
+	SQLMode         string `json:"sql_mode"`
+	CreateStatement string `json:"create_statement"`
+}
+
+// Load reads the objects of schema that pass filter, with their CREATE
+// statements, in the order Compare reports them
+func Load(ctx context.Context, db *sql.DB, schema string, filter inventory.Filter) ([]Object, error) {
+	listed, err := inventory.ObjectsMatching(ctx, db, schema, filter)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]Object, 0, len(listed))
+	for _, o := range listed {
+		def, err := ddl.ShowCreateOfType(ctx, db, schema, o.Name, o.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s.%s: %v", o.Type, schema, o.Name, err)
+		}
+		objects = append(objects, Object{Schema: schema, Name: o.Name, Type: o.Type, Definer: o.Definer,
+			SQLMode: def.SQLMode, CreateStatement: def.CreateStatement})
+	}
+	sort.Slice(objects, func(i, j int) bool { return less(objects[i].Type, objects[i].Name, objects[j].Type, objects[j].Name) })
+	return objects, nil
+}
+
+// less orders objects by type, in inventory.ObjectTypes order, then by name
+func less(type1, name1, type2, name2 string) bool {
+	if type1 != type2 {
+		return typeRank(type1) < typeRank(type2)
+	}
+	return name1 < name2
+}
+
+func typeRank(t string) int {
+	for i, known := range inventory.ObjectTypes {
+		if t == known {
+			return i
+		}
+	}
+	return len(inventory.ObjectTypes)
+}
+
+// Options controls what Compare counts as a difference
+type Options struct {
+	IgnoreDefiner    bool // leave the definer out of the comparison
+	IgnoreWhitespace bool // treat definitions that only differ in layout as equal
+
+	// Labels of the two sides in diffs, e.g. host/schema
+	SourceLabel string
+	TargetLabel string
+}
+
+// Difference is an object that is not the same on both sides
+type Difference struct {
+	Type   string
+	Name   string
+	Status string
+	Source *Object // nil for Extra
+	Target *Object // nil for Missing
+	Diff   string  // unified diff of the definitions, for Changed and DefinerChanged
+}
+
+// Compare returns the objects that are missing from target, extra in target,
+// or defined differently, ordered by type and name. The sql_mode a stored
+// program was created under is part of its definition. Tables are compared
+// without their AUTO_INCREMENT counter, and when the schemas have different
+// names, references qualified with the object's own schema are compared
+// without the qualifier.
+func Compare(source, target []Object, opts Options) []Difference {
+	targets := make(map[string]*Object)
+	for i := range target {
+		targets[key(target[i])] = &target[i]
+	}
+
+	var diffs []Difference
+	seen := make(map[string]bool)
+	for i := range source {
+		src := &source[i]
+		k := key(*src)
+		seen[k] = true
+		tgt := targets[k]
+		if tgt == nil {
+			diffs = append(diffs, Difference{Type: src.Type, Name: src.Name, Status: Missing, Source: src})
+			continue
+		}
+
+		stripSchema := src.Schema != tgt.Schema
+		srcText, tgtText := definitionText(*src, true, stripSchema), definitionText(*tgt, true, stripSchema)
+		status := ""
+		switch {
+		case !equalText(srcText, tgtText, opts.IgnoreWhitespace):
+			status = Changed
+		case !opts.IgnoreDefiner && src.Definer != tgt.Definer:
+			status = DefinerChanged
+		default:
+			continue
+		}
+		diff := UnifiedDiff(definitionText(*src, opts.IgnoreDefiner, stripSchema), definitionText(*tgt, opts.IgnoreDefiner, stripSchema),
+			label(opts.SourceLabel, *src), label(opts.TargetLabel, *tgt))
+		diffs = append(diffs, Difference{Type: src.Type, Name: src.Name, Status: status, Source: src, Target: tgt, Diff: diff})
+	}
+	for i := range target {
+		if !seen[key(target[i])] {
+			diffs = append(diffs, Difference{Type: target[i].Type, Name: target[i].Name, Status: Extra, Target: &target[i]})
+		}
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool { return less(diffs[i].Type, diffs[i].Name, diffs[j].Type, diffs[j].Name) })
+	return diffs
+}
+
+// key identifies an object within its schema
+func key(o Object) string {
+	return o.Type + "\x00" + o.Name
+}
+
+// label names one side of a diff
+func label(side string, o Object) string {
+	if side == "" {
+		side = o.Schema
+	}
+	return side + "/" + strings.ToLower(o.Type) + "/" + o.Name
+}
+
+// autoIncrement matches the table option SHOW CREATE TABLE adds for the next
+// AUTO_INCREMENT value, which differs between servers with the same table
+var autoIncrement = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+
+// definitionText is what Compare compares and diffs: the CREATE statement,
+// preceded by the sql_mode of stored programs
+func definitionText(o Object, stripDefiner, stripSchema bool) string {
+	stmt := o.CreateStatement
+	if o.Type == "TABLE" {
+		stmt = autoIncrement.ReplaceAllString(stmt, "")
+	}
+	if stripDefiner {
+		stmt = removeDefiner(stmt)
+	}
+	if stripSchema {
+		stmt = removeQualifier(stmt, o.Schema)
+	}
+	if o.SQLMode != "" {
+		stmt = "-- sql_mode: " + o.SQLMode + "\n" + stmt
+	}
+	return stmt
+}
+
+// equalText compares two definitions, optionally ignoring their layout
+func equalText(a, b string, ignoreWhitespace bool) bool {
+	if ignoreWhitespace {
+		return collapseWhitespace(a) == collapseWhitespace(b)
+	}
+	return a == b
+}
+
+// collapseWhitespace replaces every run of whitespace between tokens with a
+// single space. Literals and quoted identifiers are kept as they are.
+func collapseWhitespace(stmt string) string {
+	tokens, err := sqlparse.Tokenize(stmt)
+	if err != nil {
+		return strings.Join(strings.Fields(stmt), " ")
+	}
+	var b strings.Builder
+	for _, t := range tokens {
+		if t.Kind == sqlparse.Whitespace {
+			b.WriteByte(' ')
+			continue
+		}
+		if t.Kind == sqlparse.Comment {
+			b.WriteString(strings.TrimSpace(t.Text))
+			continue
+		}
+		b.WriteString(t.Text)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// removeDefiner drops the DEFINER clause from the head of a CREATE statement,
+// leaving the statement unchanged if it has none
+func removeDefiner(stmt string) string {
+	tokens, err := sqlparse.Tokenize(stmt)
+	if err != nil {
+		return stmt
+	}
+	var sig []sqlparse.Token
+	for _, t := range tokens {
+		if t.Significant() {
+			sig = append(sig, t)
+		}
+	}
+	for i, t := range sig {
+		// The clause comes before the body, so the first DEFINER is it
+		if !t.Is("DEFINER") {
+			continue
+		}
+		if i+2 >= len(sig) || !sig[i+1].IsPunct("=") {
+			return stmt
+		}
+		end := sig[i+2].End
+		switch {
+		case i+3 < len(sig) && sig[i+3].Kind == sqlparse.Variable:
+			// An unquoted host such as @localhost is lexed as a variable
+			end = sig[i+3].End
+		case i+4 < len(sig) && sig[i+3].IsPunct("@"):
+			end = sig[i+4].End
+		}
+		rest := strings.TrimLeft(stmt[end:], " \t\r\n")
+		return stmt[:t.Start] + rest
+	}
+	return stmt
+}
+
+// removeQualifier drops schema. from the names qualified with it
+func removeQualifier(stmt, schema string) string {
+	tokens, err := sqlparse.Tokenize(stmt)
+	if err != nil {
+		return stmt
+	}
+	var b strings.Builder
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		qualifier := (t.Kind == sqlparse.QuotedIdentifier || t.Kind == sqlparse.Word) && t.Value() == schema
+		if qualifier && i+1 < len(tokens) && tokens[i+1].IsPunct(".") {
+			i++
+			continue
+		}
+		b.WriteString(t.Text)
+	}
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package schemadiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	proc := func(schema, definer, body string) Object {
+		return Object{Schema: schema, Name: "get_x", Type: "PROCEDURE", Definer: definer, SQLMode: "STRICT_TRANS_TABLES",
+			CreateStatement: "CREATE DEFINER=`" + strings.Replace(definer, "@", "`@`", 1) + "` PROCEDURE `get_x`()\nBEGIN\n" + body + "\nEND"}
+	}
+	view := func(schema, query string) Object {
+		return Object{Schema: schema, Name: "v", Type: "VIEW", Definer: "app@%",
+			CreateStatement: "CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `v` AS " + query}
+	}
+	table := func(schema, counter string) Object {
+		return Object{Schema: schema, Name: "t", Type: "TABLE",
+			CreateStatement: "CREATE TABLE `t` (\n  `id` int NOT NULL AUTO_INCREMENT\n) ENGINE=InnoDB" + counter + " DEFAULT CHARSET=utf8mb4"}
+	}
+
+	tests := []struct {
+		name   string
+		source []Object
+		target []Object
+		opts   Options
+		want   []string // type name status
+	}{
+		{
+			name:   "missing and extra",
+			source: []Object{proc("app", "app@%", "  SELECT 1;"), view("app", "select 1 AS `x`")},
+			target: []Object{view("app", "select 1 AS `x`"), {Schema: "app", Name: "e", Type: "EVENT"}},
+			want:   []string{"PROCEDURE get_x MISSING", "EVENT e EXTRA"},
+		},
+		{
+			name:   "changed body",
+			source: []Object{proc("app", "app@%", "  SELECT 1;")},
+			target: []Object{proc("app", "app@%", "  SELECT 2;")},
+			want:   []string{"PROCEDURE get_x CHANGED"},
+		},
+		{
+			name:   "definer only",
+			source: []Object{proc("app", "app@%", "  SELECT 1;")},
+			target: []Object{proc("app", "svc@%", "  SELECT 1;")},
+			want:   []string{"PROCEDURE get_x DEFINER_CHANGED"},
+		},
+		{
+			name:   "definer ignored",
+			source: []Object{proc("app", "app@%", "  SELECT 1;")},
+			target: []Object{proc("app", "svc@%", "  SELECT 1;")},
+			opts:   Options{IgnoreDefiner: true},
+		},
+		{
+			name:   "layout",
+			source: []Object{proc("app", "app@%", "  SELECT 1;")},
+			target: []Object{proc("app", "app@%", "\tSELECT   1;")},
+			want:   []string{"PROCEDURE get_x CHANGED"},
+		},
+		{
+			name:   "layout ignored",
+			source: []Object{proc("app", "app@%", "  SELECT 1;")},
+			target: []Object{proc("app", "app@%", "\tSELECT   1;")},
+			opts:   Options{IgnoreWhitespace: true},
+		},
+		{
+			name:   "own schema qualifiers and AUTO_INCREMENT",
+			source: []Object{view("app", "select `app`.`t`.`id` AS `id` from `app`.`t`"), table("app", " AUTO_INCREMENT=42")},
+			target: []Object{view("app_restore", "select `app_restore`.`t`.`id` AS `id` from `app_restore`.`t`"), table("app_restore", "")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range Compare(tt.source, tt.target, tt.opts) {
+				got = append(got, d.Type+" "+d.Name+" "+d.Status)
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("Compare() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	diffs := Compare([]Object{proc("app", "app@%", "  SELECT 1;")}, []Object{proc("app", "svc@%", "  SELECT 2;")},
+		Options{IgnoreDefiner: true, SourceLabel: "primary/app", TargetLabel: "replica/app"})
+	want := "--- primary/app/procedure/get_x\n+++ replica/app/procedure/get_x\n@@ -1,5 +1,5 @@\n" +
+		" -- sql_mode: STRICT_TRANS_TABLES\n CREATE PROCEDURE `get_x`()\n BEGIN\n-  SELECT 1;\n+  SELECT 2;\n END\n"
+	if len(diffs) != 1 || diffs[0].Diff != want {
+		t.Errorf("Compare() diff =\n%v\nwant\n%s", diffs, want)
+	}
+}
+
+func TestRemoveDefiner(t *testing.T) {
+	tests := []struct{ stmt, want string }{
+		{"CREATE DEFINER=`app`@`%` PROCEDURE `p`() SELECT 1", "CREATE PROCEDURE `p`() SELECT 1"},
+		{"CREATE ALGORITHM=MERGE DEFINER=`a`@`10.%` SQL SECURITY DEFINER VIEW `v` AS select 1", "CREATE ALGORITHM=MERGE SQL SECURITY DEFINER VIEW `v` AS select 1"},
+		{"CREATE DEFINER = root@localhost TRIGGER t BEFORE INSERT ON x FOR EACH ROW SET @a = 1", "CREATE TRIGGER t BEFORE INSERT ON x FOR EACH ROW SET @a = 1"},
+		{"CREATE TABLE `t` (`id` int)", "CREATE TABLE `t` (`id` int)"},
+	}
+	for _, tt := range tests {
+		if got := removeDefiner(tt.stmt); got != tt.want {
+			t.Errorf("removeDefiner(%q) = %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+}
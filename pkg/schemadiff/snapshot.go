@@ -0,0 +1,110 @@
+package schemadiff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format WriteSnapshot writes
+const SnapshotVersion = 1
+
+// Statuses of a Difference returned by Drift
+const (
+	Added    = "ADDED"    // created since the snapshot
+	Removed  = "REMOVED"  // dropped since the snapshot
+	Modified = "MODIFIED" // redefined since the snapshot
+)
+
+// Snapshot is the state of a schema's objects at one point in time, kept as
+// a baseline to detect drift against
+type Snapshot struct {
+	Version int              `json:"version"`
+	Host    string           `json:"host"`
+	Schema  string           `json:"schema"`
+	TakenAt time.Time        `json:"taken_at"`
+	Objects []SnapshotObject `json:"objects"`
+}
+
+// SnapshotObject is an object of a snapshot with the checksum of its
+// definition
+type SnapshotObject struct {
+	Object
+	Checksum string `json:"checksum"`
+}
+
+// NewSnapshot records objects of host and schema, as returned by Load
+func NewSnapshot(host, schema string, objects []Object, takenAt time.Time) Snapshot {
+	s := Snapshot{Version: SnapshotVersion, Host: host, Schema: schema, TakenAt: takenAt.UTC(),
+		Objects: make([]SnapshotObject, len(objects))}
+	for i, o := range objects {
+		s.Objects[i] = SnapshotObject{Object: o, Checksum: Checksum(o)}
+	}
+	return s
+}
+
+// Checksum returns the SHA-256 of an object's definition, as Compare sees it
+// with the definer included
+func Checksum(o Object) string {
+	sum := sha256.Sum256([]byte(o.Definer + "\n" + definitionText(o, false, false)))
+	return hex.EncodeToString(sum[:])
+}
+
+// List returns the objects of the snapshot
+func (s Snapshot) List() []Object {
+	objects := make([]Object, len(s.Objects))
+	for i, o := range s.Objects {
+		objects[i] = o.Object
+	}
+	return objects
+}
+
+// WriteSnapshot writes s as indented JSON
+func WriteSnapshot(w io.Writer, s Snapshot) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot. Objects whose
+// checksum does not match their definition are rejected, so a damaged or
+// hand-edited baseline is not trusted.
+func ReadSnapshot(r io.Reader) (Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return s, fmt.Errorf("error reading snapshot: %v", err)
+	}
+	if s.Version != SnapshotVersion {
+		return s, fmt.Errorf("unsupported snapshot version %d, expected %d", s.Version, SnapshotVersion)
+	}
+	for _, o := range s.Objects {
+		if o.Checksum != Checksum(o.Object) {
+			return s, fmt.Errorf("snapshot object %s %s.%s: checksum mismatch", o.Type, o.Schema, o.Name)
+		}
+	}
+	return s, nil
+}
+
+// Drift compares the objects of a snapshot with those of the live schema.
+// It reports the same differences as Compare, but as objects Added to,
+// Removed from or Modified on the live schema.
+func Drift(baseline Snapshot, live []Object, opts Options) []Difference {
+	diffs := Compare(baseline.List(), live, opts)
+	for i := range diffs {
+		switch diffs[i].Status {
+		case Missing:
+			diffs[i].Status = Removed
+		case Extra:
+			diffs[i].Status = Added
+		case Changed:
+			diffs[i].Status = Modified
+		}
+	}
+	return diffs
+}
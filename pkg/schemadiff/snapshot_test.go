@@ -0,0 +1,64 @@
+package schemadiff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	objects := []Object{
+		{Schema: "app", Name: "get_x", Type: "PROCEDURE", Definer: "app@%", SQLMode: "STRICT_TRANS_TABLES",
+			CreateStatement: "CREATE DEFINER=`app`@`%` PROCEDURE `get_x`()\nSELECT 1"},
+		{Schema: "app", Name: "v", Type: "VIEW", Definer: "app@%",
+			CreateStatement: "CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `v` AS select 1 AS `x`"},
+	}
+	taken := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, NewSnapshot("primary", "app", objects, taken)); err != nil {
+		t.Fatal(err)
+	}
+	s, err := ReadSnapshot(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Host != "primary" || s.Schema != "app" || !s.TakenAt.Equal(taken) || len(s.Objects) != 2 {
+		t.Fatalf("ReadSnapshot() = %+v", s)
+	}
+	if diffs := Drift(s, objects, Options{}); len(diffs) != 0 {
+		t.Errorf("Drift() against the same objects = %v", diffs)
+	}
+
+	edited := strings.Replace(buf.String(), "SELECT 1", "SELECT 2", 1)
+	if _, err := ReadSnapshot(strings.NewReader(edited)); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("ReadSnapshot() of an edited snapshot: error = %v", err)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	proc := func(name, definer, body string) Object {
+		return Object{Schema: "app", Name: name, Type: "PROCEDURE", Definer: definer,
+			CreateStatement: "CREATE DEFINER=`" + strings.Replace(definer, "@", "`@`", 1) + "` PROCEDURE `" + name + "`()\n" + body}
+	}
+	baseline := NewSnapshot("primary", "app", []Object{
+		proc("a", "app@%", "SELECT 1"),
+		proc("b", "app@%", "SELECT 1"),
+		proc("c", "app@%", "SELECT 1"),
+	}, time.Now())
+	live := []Object{
+		proc("b", "app@%", "SELECT 2"),
+		proc("c", "root@localhost", "SELECT 1"),
+		proc("d", "app@%", "SELECT 1"),
+	}
+
+	var got []string
+	for _, d := range Drift(baseline, live, Options{}) {
+		got = append(got, d.Name+" "+d.Status)
+	}
+	want := "a REMOVED, b MODIFIED, c DEFINER_CHANGED, d ADDED"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("Drift() = %v, want %v", got, want)
+	}
+}
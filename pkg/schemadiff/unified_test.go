@@ -0,0 +1,32 @@
+package schemadiff
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "equal", a: "x\ny", b: "x\ny", want: ""},
+		{
+			name: "one changed line",
+			a:    "BEGIN\n  SELECT 1;\nEND",
+			b:    "BEGIN\n  SELECT 2;\nEND",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n BEGIN\n-  SELECT 1;\n+  SELECT 2;\n END\n",
+		},
+		{
+			name: "distant changes get their own hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12",
+			b:    "1a\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+1a\n 2\n 3\n 4\n@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff(tt.a, tt.b, "a", "b"); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
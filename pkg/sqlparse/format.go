@@ -0,0 +1,435 @@
+package sqlparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Keyword cases accepted by FormatOptions
+const (
+	KeywordUpper    = "upper"
+	KeywordLower    = "lower"
+	KeywordPreserve = "preserve"
+)
+
+// FormatOptions controls the layout Format produces
+type FormatOptions struct {
+	KeywordCase string // upper (default), lower or preserve
+	Indent      string // one level of indentation, four spaces by default
+}
+
+// ValidateKeywordCase lower-cases and checks a keyword case
+func ValidateKeywordCase(keywordCase string) (string, error) {
+	keywordCase = strings.ToLower(keywordCase)
+	switch keywordCase {
+	case KeywordUpper, KeywordLower, KeywordPreserve:
+		return keywordCase, nil
+	}
+	return "", fmt.Errorf("invalid keyword case: %s. Must be upper, lower or preserve", keywordCase)
+}
+
+// keywords are the words Format changes the case of. Only reserved words and
+// the clauses of CREATE VIEW are listed, so identifiers are left alone.
+var keywords = map[string]bool{
+	"ALGORITHM": true, "ALL": true, "ALTER": true, "AND": true, "AS": true, "ASC": true,
+	"BETWEEN": true, "BY": true, "CASCADED": true, "CASE": true, "CHECK": true, "CREATE": true,
+	"CROSS": true, "DEFINER": true, "DESC": true, "DISTINCT": true, "DIV": true, "ELSE": true,
+	"END": true, "EXISTS": true, "FALSE": true, "FOR": true, "FROM": true, "GROUP": true,
+	"HAVING": true, "IN": true, "INNER": true, "INTERVAL": true, "INVOKER": true, "IS": true,
+	"JOIN": true, "LEFT": true, "LIKE": true, "LIMIT": true, "LOCAL": true, "MERGE": true,
+	"MOD": true, "NATURAL": true, "NOT": true, "NULL": true, "ON": true, "OPTION": true,
+	"OR": true, "ORDER": true, "OUTER": true, "OVER": true, "PARTITION": true, "REGEXP": true,
+	"REPLACE": true, "RIGHT": true, "ROLLUP": true, "SECURITY": true, "SELECT": true, "SQL": true,
+	"STRAIGHT_JOIN": true, "TEMPTABLE": true, "THEN": true, "TRUE": true, "UNDEFINED": true,
+	"UNION": true, "USING": true, "VIEW": true, "WHEN": true, "WHERE": true, "WINDOW": true,
+	"WITH": true, "XOR": true,
+}
+
+// Clauses that start a line of their own, longest phrases first
+var clausePhrases = [][]string{
+	{"GROUP", "BY"}, {"ORDER", "BY"}, {"UNION", "ALL"}, {"UNION", "DISTINCT"},
+	{"SELECT"}, {"FROM"}, {"WHERE"}, {"HAVING"}, {"LIMIT"}, {"UNION"}, {"WINDOW"},
+}
+
+// Joins start an indented line of their own
+var joinPhrases = [][]string{
+	{"NATURAL", "LEFT", "OUTER", "JOIN"}, {"NATURAL", "RIGHT", "OUTER", "JOIN"},
+	{"LEFT", "OUTER", "JOIN"}, {"RIGHT", "OUTER", "JOIN"}, {"NATURAL", "LEFT", "JOIN"},
+	{"NATURAL", "RIGHT", "JOIN"}, {"NATURAL", "INNER", "JOIN"},
+	{"LEFT", "JOIN"}, {"RIGHT", "JOIN"}, {"INNER", "JOIN"}, {"CROSS", "JOIN"}, {"NATURAL", "JOIN"},
+	{"JOIN"}, {"STRAIGHT_JOIN"},
+}
+
+// View header clauses that start a line of their own
+var headerPhrases = [][]string{
+	{"ALGORITHM"}, {"DEFINER"}, {"SQL", "SECURITY"}, {"VIEW"},
+}
+
+// Modifiers that stay on the line of SELECT
+var selectModifiers = map[string]bool{
+	"ALL": true, "DISTINCT": true, "DISTINCTROW": true, "HIGH_PRIORITY": true, "STRAIGHT_JOIN": true,
+	"SQL_SMALL_RESULT": true, "SQL_BIG_RESULT": true, "SQL_BUFFER_RESULT": true,
+	"SQL_CACHE": true, "SQL_NO_CACHE": true, "SQL_CALC_FOUND_ROWS": true,
+}
+
+// Kinds of nesting the formatter tracks
+const (
+	frameQuery  = iota // the statement or a subquery: clauses break lines
+	frameTables        // parentheses around joins: joins break lines
+	frameParen         // any other parentheses: laid out inline
+	frameCase          // CASE ... END: WHEN and ELSE break lines
+)
+
+type frame struct {
+	kind   int
+	indent int    // indentation level of the frame's own lines
+	clause string // the clause a query frame is in, e.g. SELECT
+}
+
+// formatter lays out the tokens of one or more statements
+type formatter struct {
+	opts   FormatOptions
+	tokens []Token
+	sig    []int // indexes of the significant tokens
+	out    strings.Builder
+	stack  []frame
+	line   int  // indentation level of the current line
+	space  bool // whitespace is pending before the next token
+	fresh  bool // nothing has been written on the current line yet
+	header bool // inside the header of CREATE or ALTER VIEW
+}
+
+// Format pretty-prints MySQL statements. Clauses start new lines, select
+// lists have one expression per line, joins, subqueries and CASE branches are
+// indented, and keywords are re-cased. Only whitespace between tokens and the
+// case of keywords change: quoted identifiers, string literals and comments
+// are copied verbatim, so the result means exactly what the input did.
+func Format(src string, opts FormatOptions) (string, error) {
+	if opts.KeywordCase == "" {
+		opts.KeywordCase = KeywordUpper
+	}
+	keywordCase, err := ValidateKeywordCase(opts.KeywordCase)
+	if err != nil {
+		return "", err
+	}
+	opts.KeywordCase = keywordCase
+	if opts.Indent == "" {
+		opts.Indent = "    "
+	}
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return "", err
+	}
+	f := &formatter{opts: opts, tokens: tokens, fresh: true}
+	for i, t := range tokens {
+		if t.Significant() {
+			f.sig = append(f.sig, i)
+		}
+	}
+	f.startStatement(0)
+	f.run()
+	return strings.TrimSpace(f.out.String()), nil
+}
+
+// startStatement resets the layout for the statement whose first significant
+// token is sig[s]. The clauses of CREATE and ALTER VIEW are indented one
+// level, and so is the query below them.
+func (f *formatter) startStatement(s int) {
+	f.header = false
+	base := 0
+	if s < len(f.sig) {
+		first := f.tokens[f.sig[s]]
+		if (first.Is("CREATE") || first.Is("ALTER")) && f.viewAhead(s) {
+			f.header = true
+			base = 1
+		}
+	}
+	f.stack = []frame{{kind: frameQuery, indent: base}}
+}
+
+// viewAhead reports whether VIEW appears before the statement's first AS
+func (f *formatter) viewAhead(s int) bool {
+	for ; s < len(f.sig); s++ {
+		t := f.tokens[f.sig[s]]
+		if t.Is("VIEW") {
+			return true
+		}
+		if t.Is("AS") || t.IsPunct(";") || t.IsPunct("(") {
+			return false
+		}
+	}
+	return false
+}
+
+func (f *formatter) top() *frame {
+	return &f.stack[len(f.stack)-1]
+}
+
+// query returns the innermost query frame and whether only join parentheses
+// separate it from the current position
+func (f *formatter) query() (*frame, bool) {
+	direct := true
+	for i := len(f.stack) - 1; i > 0; i-- {
+		switch f.stack[i].kind {
+		case frameQuery:
+			return &f.stack[i], direct
+		case frameParen, frameCase:
+			direct = false
+		}
+	}
+	return &f.stack[0], direct
+}
+
+func (f *formatter) run() {
+	s := 0 // position in sig of the next significant token
+	for i := 0; i < len(f.tokens); i++ {
+		t := f.tokens[i]
+		switch t.Kind {
+		case Whitespace:
+			f.space = true
+			continue
+		case Comment:
+			f.write(t.Text)
+			// A line comment runs to the end of the line
+			if !strings.HasPrefix(t.Text, "/*") {
+				f.newline(f.line)
+			}
+			continue
+		}
+
+		switch n := f.phrase(s); {
+		case n > 0:
+			i = f.writePhrase(s, n)
+			s += n
+			continue
+		case t.IsPunct(";"):
+			f.space = false
+			f.write(";")
+			f.newline(0)
+			f.startStatement(s + 1)
+		case t.IsPunct("("):
+			f.openParen(s)
+		case t.IsPunct(")"):
+			f.closeParen()
+		case t.IsPunct(","):
+			f.space = false
+			f.write(",")
+			if q := f.top(); q.kind == frameQuery && q.clause == "SELECT" {
+				f.newline(q.indent + 1)
+			}
+		default:
+			f.write(f.word(s))
+		}
+		s++
+	}
+}
+
+// phrase returns how many significant tokens from sig[s] form a clause,
+// join, CASE keyword or view header clause that changes the layout, or 0
+func (f *formatter) phrase(s int) int {
+	t := f.tokens[f.sig[s]]
+	if t.Kind != Word || f.qualified(s) {
+		return 0
+	}
+	if f.header {
+		if len(f.stack) > 1 {
+			return 0
+		}
+		// AS after the view name ends the header
+		if t.Is("AS") {
+			return 1
+		}
+		// SQL SECURITY DEFINER is not a DEFINER clause
+		if t.Is("DEFINER") && s > 0 && f.tokens[f.sig[s-1]].Is("SECURITY") {
+			return 0
+		}
+		return f.match(s, headerPhrases)
+	}
+	if t.Is("CASE") || f.top().kind == frameCase && (t.Is("WHEN") || t.Is("ELSE") || t.Is("END")) {
+		return 1
+	}
+	if _, direct := f.query(); !direct {
+		return 0
+	}
+	if f.top().kind == frameQuery {
+		if n := f.match(s, clausePhrases); n > 0 {
+			if t.Is("SELECT") {
+				for s+n < len(f.sig) && f.tokens[f.sig[s+n]].Kind == Word && selectModifiers[strings.ToUpper(f.tokens[f.sig[s+n]].Text)] {
+					n++
+				}
+			}
+			return n
+		}
+	}
+	return f.match(s, joinPhrases)
+}
+
+// match returns the length of the first phrase that starts at sig[s]
+func (f *formatter) match(s int, phrases [][]string) int {
+	for _, phrase := range phrases {
+		if s+len(phrase) > len(f.sig) {
+			continue
+		}
+		ok := true
+		for j, word := range phrase {
+			if !f.tokens[f.sig[s+j]].Is(word) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return len(phrase)
+		}
+	}
+	return 0
+}
+
+// writePhrase lays out the n-token phrase starting at sig[s] and returns the
+// index of its last token
+func (f *formatter) writePhrase(s, n int) int {
+	t := f.tokens[f.sig[s]]
+	q, _ := f.query()
+	clause := !f.header && f.top().kind == frameQuery && f.match(s, clausePhrases) > 0
+
+	// Break the line before the phrase
+	switch {
+	case f.header && t.Is("AS"), t.Is("CASE"):
+	case f.header:
+		f.newline(q.indent)
+	case t.Is("WHEN") || t.Is("ELSE"):
+		f.newline(f.top().indent)
+	case t.Is("END"):
+		f.newline(f.top().indent - 1)
+		f.stack = f.stack[:len(f.stack)-1]
+	case clause:
+		q.clause = strings.ToUpper(t.Text)
+		f.newline(q.indent)
+	default:
+		// Joins are indented below FROM
+		f.newline(q.indent + 1)
+	}
+
+	// Keep the words of the phrase and any comments between them
+	last := f.sig[s+n-1]
+	for i := f.sig[s]; i <= last; i++ {
+		switch f.tokens[i].Kind {
+		case Whitespace:
+			f.space = true
+		case Comment:
+			f.write(f.tokens[i].Text)
+		default:
+			f.write(f.casedKeyword(f.tokens[i].Text))
+		}
+	}
+
+	// Break the line after the phrase
+	switch {
+	case f.header && t.Is("AS"):
+		f.header = false
+		f.newline(q.indent)
+	case t.Is("CASE"):
+		f.stack = append(f.stack, frame{kind: frameCase, indent: f.line + 1})
+	case clause && q.clause == "SELECT":
+		f.newline(q.indent + 1)
+	}
+	return last
+}
+
+// openParen opens a subquery, a group of joins or plain parentheses
+func (f *formatter) openParen(s int) {
+	f.write("(")
+	var prev, next Token
+	if s > 0 {
+		prev = f.tokens[f.sig[s-1]]
+	}
+	if s+1 < len(f.sig) {
+		next = f.tokens[f.sig[s+1]]
+	}
+	q, direct := f.query()
+	switch {
+	case f.header:
+		f.stack = append(f.stack, frame{kind: frameParen, indent: f.line})
+	case next.Is("SELECT") || next.Is("WITH"):
+		f.stack = append(f.stack, frame{kind: frameQuery, indent: f.line + 1})
+	case direct && q.clause == "FROM" && (prev.Is("FROM") || prev.Is("JOIN") || prev.IsPunct("(") || prev.IsPunct(",")):
+		f.stack = append(f.stack, frame{kind: frameTables, indent: f.line})
+	default:
+		f.stack = append(f.stack, frame{kind: frameParen, indent: f.line})
+	}
+}
+
+// closeParen closes the innermost parentheses. A subquery's closing
+// parenthesis goes on a line of its own.
+func (f *formatter) closeParen() {
+	f.space = false
+	// An unterminated CASE ends with the parentheses around it
+	for len(f.stack) > 1 && f.top().kind == frameCase {
+		f.stack = f.stack[:len(f.stack)-1]
+	}
+	if len(f.stack) > 1 {
+		closed := f.stack[len(f.stack)-1]
+		f.stack = f.stack[:len(f.stack)-1]
+		if closed.kind == frameQuery {
+			f.newline(closed.indent - 1)
+		}
+	}
+	f.write(")")
+}
+
+// word returns the text of the word at sig[s], re-cased if it is a keyword
+// rather than part of a qualified name
+func (f *formatter) word(s int) string {
+	t := f.tokens[f.sig[s]]
+	if t.Kind != Word || f.qualified(s) {
+		return t.Text
+	}
+	if s+1 < len(f.sig) && f.tokens[f.sig[s+1]].IsPunct(".") {
+		return t.Text
+	}
+	return f.casedKeyword(t.Text)
+}
+
+// qualified reports whether the word at sig[s] follows a dot, as in t.name
+func (f *formatter) qualified(s int) bool {
+	return s > 0 && f.tokens[f.sig[s-1]].IsPunct(".")
+}
+
+func (f *formatter) casedKeyword(word string) string {
+	if !keywords[strings.ToUpper(word)] {
+		return word
+	}
+	switch f.opts.KeywordCase {
+	case KeywordUpper:
+		return strings.ToUpper(word)
+	case KeywordLower:
+		return strings.ToLower(word)
+	}
+	return word
+}
+
+// write appends text to the current line, preceded by a space if the input
+// had whitespace there
+func (f *formatter) write(text string) {
+	if f.fresh {
+		f.out.WriteString(strings.Repeat(f.opts.Indent, f.line))
+	} else if f.space {
+		f.out.WriteByte(' ')
+	}
+	f.out.WriteString(text)
+	f.space = false
+	f.fresh = false
+}
+
+// newline starts a new line indented level times. An empty line is not
+// ended, only re-indented.
+func (f *formatter) newline(level int) {
+	if level < 0 {
+		level = 0
+	}
+	if !f.fresh {
+		f.out.WriteByte('\n')
+	}
+	f.line = level
+	f.space = false
+	f.fresh = true
+}
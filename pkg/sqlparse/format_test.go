@@ -0,0 +1,64 @@
+package sqlparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		opts FormatOptions
+		want string
+	}{
+		{
+			name: "view header, select list and joins",
+			src:  "ALTER ALGORITHM = MERGE DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `db`.`v` AS select `t`.`a` AS `a`,`u`.`b` AS `b` from (`t` left join `u` on((`t`.`id` = `u`.`id`))) where (`t`.`x` > 1) order by `t`.`a` desc;",
+			want: "ALTER\n    ALGORITHM = MERGE\n    DEFINER=`app`@`%`\n    SQL SECURITY DEFINER\n    VIEW `db`.`v` AS\n    SELECT\n        `t`.`a` AS `a`,\n        `u`.`b` AS `b`\n" +
+				"    FROM (`t`\n        LEFT JOIN `u` ON((`t`.`id` = `u`.`id`)))\n    WHERE (`t`.`x` > 1)\n    ORDER BY `t`.`a` DESC;",
+		},
+		{
+			name: "keywords inside literals, identifiers and comments are kept",
+			src:  "select 'a  from   b' AS `where`, /* left join */ `x`.`from` from t",
+			opts: FormatOptions{KeywordCase: KeywordLower},
+			want: "select\n    'a  from   b' as `where`,\n    /* left join */ `x`.`from`\nfrom t",
+		},
+		{
+			name: "subqueries and CASE are indented",
+			src:  "SELECT CASE WHEN a = 1 THEN 'x' ELSE 'y' END AS k, (SELECT max(id) FROM u) AS m FROM t",
+			opts: FormatOptions{KeywordCase: KeywordPreserve},
+			want: "SELECT\n    CASE\n        WHEN a = 1 THEN 'x'\n        ELSE 'y'\n    END AS k,\n    (\n        SELECT\n            max(id)\n        FROM u\n    ) AS m\nFROM t",
+		},
+		{
+			name: "line comments end the line",
+			src:  "select distinct a -- from here\nfrom t union all select b from u",
+			want: "SELECT DISTINCT\n    a -- from here\nFROM t\nUNION ALL\nSELECT\n    b\nFROM u",
+		},
+		{
+			name: "function arguments stay inline",
+			src:  "select group_concat(a order by b separator ',') from t group by c",
+			want: "SELECT\n    group_concat(a ORDER BY b separator ',')\nFROM t\nGROUP BY c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format(tt.src, tt.opts)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Format() =\n%s\nwant\n%s", got, tt.want)
+			}
+			// Only whitespace and keyword case may change
+			if !strings.EqualFold(strings.Join(strings.Fields(got), ""), strings.Join(strings.Fields(tt.src), "")) {
+				t.Errorf("Format() changed more than whitespace and case:\n%s", got)
+			}
+		})
+	}
+
+	if _, err := Format("select 1", FormatOptions{KeywordCase: "title"}); err == nil {
+		t.Error("Format accepted an invalid keyword case")
+	}
+}
@@ -0,0 +1,298 @@
+// Package sqlparse tokenizes MySQL statements and parses the ones go-pvt
+// needs to understand, such as CREATE VIEW.
+package sqlparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TokenKind classifies a token
+type TokenKind int
+
+const (
+	Whitespace TokenKind = iota
+	Comment
+	Word             // keywords and bare identifiers
+	QuotedIdentifier // `name`
+	String           // 'text' or "text"
+	Number
+	Variable // @var, @@var
+	Punct    // operators, parentheses, commas, dots, @
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case Whitespace:
+		return "Whitespace"
+	case Comment:
+		return "Comment"
+	case Word:
+		return "Word"
+	case QuotedIdentifier:
+		return "QuotedIdentifier"
+	case String:
+		return "String"
+	case Number:
+		return "Number"
+	case Variable:
+		return "Variable"
+	case Punct:
+		return "Punct"
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(k))
+}
+
+// Token is a lexical unit of a statement. Start and End are byte offsets
+// into the source, so Text is always src[Start:End].
+type Token struct {
+	Kind  TokenKind
+	Text  string
+	Start int
+	End   int
+}
+
+// Is reports whether the token is the given keyword, ignoring case
+func (t Token) Is(keyword string) bool {
+	return t.Kind == Word && strings.EqualFold(t.Text, keyword)
+}
+
+// IsPunct reports whether the token is the given punctuation
+func (t Token) IsPunct(p string) bool {
+	return t.Kind == Punct && t.Text == p
+}
+
+// Value returns the unquoted value of identifiers and strings, and the text otherwise
+func (t Token) Value() string {
+	switch t.Kind {
+	case QuotedIdentifier:
+		return strings.ReplaceAll(t.Text[1:len(t.Text)-1], "``", "`")
+	case String:
+		return unquoteString(t.Text)
+	}
+	return t.Text
+}
+
+// Significant reports whether the token carries meaning, i.e. is not whitespace or a comment
+func (t Token) Significant() bool {
+	return t.Kind != Whitespace && t.Kind != Comment
+}
+
+// Multi-character operators, longest first
+var operators = []string{"<=>", "<<", ">>", "<=", ">=", "<>", "!=", ":=", "||", "&&", "->>", "->"}
+
+// Tokenize splits a MySQL statement into tokens, including whitespace and
+// comments, so that concatenating the token texts reproduces src exactly.
+func Tokenize(src string) ([]Token, error) {
+	var tokens []Token
+	i := 0
+	for i < len(src) {
+		start := i
+		c := src[i]
+		var kind TokenKind
+
+		switch {
+		case isSpace(c):
+			for i < len(src) && isSpace(src[i]) {
+				i++
+			}
+			kind = Whitespace
+
+		case c == '#' || (c == '-' && strings.HasPrefix(src[i:], "--") && (i+2 == len(src) || isSpace(src[i+2]))):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			kind = Comment
+
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end == -1 {
+				return tokens, fmt.Errorf("unterminated comment at offset %d", start)
+			}
+			i += 2 + end + 2
+			kind = Comment
+
+		case c == '`':
+			end, ok := scanQuoted(src, i, '`', false)
+			if !ok {
+				return tokens, fmt.Errorf("unterminated quoted identifier at offset %d", start)
+			}
+			i = end
+			kind = QuotedIdentifier
+
+		case c == '\'' || c == '"':
+			end, ok := scanQuoted(src, i, c, true)
+			if !ok {
+				return tokens, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			i = end
+			kind = String
+
+		case c == '@' && i+1 < len(src) && (isWordChar(src[i+1]) || src[i+1] == '@'):
+			i++
+			if src[i] == '@' {
+				i++
+			}
+			for i < len(src) && (isWordChar(src[i]) || src[i] == '.') {
+				i++
+			}
+			kind = Variable
+
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1]) && !prevIsIdentifier(tokens)):
+			i = scanNumber(src, i)
+			// Identifiers may start with digits, e.g. 1st_table
+			if i < len(src) && isWordChar(src[i]) && !isDigit(src[i]) {
+				for i < len(src) && isWordChar(src[i]) {
+					i++
+				}
+				kind = Word
+			} else {
+				kind = Number
+			}
+
+		case isWordChar(c):
+			for i < len(src) && isWordChar(src[i]) {
+				i++
+			}
+			kind = Word
+			// Charset introducers such as _utf8mb4'text' belong to the string
+			if c == '_' && i < len(src) && (src[i] == '\'' || src[i] == '"') {
+				if end, ok := scanQuoted(src, i, src[i], true); ok {
+					i = end
+					kind = String
+				}
+			}
+
+		default:
+			i++
+			for _, op := range operators {
+				if strings.HasPrefix(src[start:], op) {
+					i = start + len(op)
+					break
+				}
+			}
+			kind = Punct
+		}
+
+		tokens = append(tokens, Token{Kind: kind, Text: src[start:i], Start: start, End: i})
+	}
+	return tokens, nil
+}
+
+// scanQuoted returns the offset just past the quoted text starting at i.
+// Doubled quotes are always escapes; backslash escapes only apply to strings.
+func scanQuoted(src string, i int, quote byte, backslash bool) (int, bool) {
+	i++
+	for i < len(src) {
+		switch {
+		case backslash && src[i] == '\\':
+			i += 2
+			continue
+		case src[i] == quote:
+			if i+1 < len(src) && src[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1, true
+		}
+		i++
+	}
+	return i, false
+}
+
+func scanNumber(src string, i int) int {
+	if strings.HasPrefix(src[i:], "0x") || strings.HasPrefix(src[i:], "0b") {
+		i += 2
+		for i < len(src) && isWordChar(src[i]) {
+			i++
+		}
+		return i
+	}
+	for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+		i++
+	}
+	// Exponent, e.g. 1e10 or 1.5E-3
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		j := i + 1
+		if j < len(src) && (src[j] == '+' || src[j] == '-') {
+			j++
+		}
+		if j < len(src) && isDigit(src[j]) {
+			i = j
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+// prevIsIdentifier reports whether the last significant token could be
+// followed by a qualifying dot, as in t.1col
+func prevIsIdentifier(tokens []Token) bool {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].Significant() {
+			return tokens[i].Kind == Word || tokens[i].Kind == QuotedIdentifier
+		}
+	}
+	return false
+}
+
+func unquoteString(s string) string {
+	// Drop a charset introducer such as _utf8mb4
+	if s[0] == '_' {
+		s = s[strings.IndexAny(s, `'"`):]
+	}
+	quote := s[0]
+	inner := s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		if c == quote && i+1 < len(inner) && inner[i+1] == quote {
+			b.WriteByte(quote)
+			i++
+			continue
+		}
+		if c == '\\' && i+1 < len(inner) {
+			i++
+			switch inner[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			case 'b':
+				b.WriteByte('\b')
+			case 'Z':
+				b.WriteByte(26)
+			default:
+				b.WriteByte(inner[i])
+			}
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isWordChar reports whether c can be part of a bare identifier. Bytes above
+// 0x7f are accepted so that UTF-8 identifiers stay in one token.
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// QuoteIdentifier backtick-quotes a name
+func QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,95 @@
+package sqlparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		kinds []TokenKind
+		texts []string
+	}{
+		{
+			name:  "quoted identifier with escaped backtick",
+			src:   "`a``b`.c",
+			kinds: []TokenKind{QuotedIdentifier, Punct, Word},
+			texts: []string{"`a``b`", ".", "c"},
+		},
+		{
+			name:  "strings with escapes and doubled quotes",
+			src:   `'it''s' "a\"b"`,
+			kinds: []TokenKind{String, Whitespace, String},
+			texts: []string{`'it''s'`, " ", `"a\"b"`},
+		},
+		{
+			name:  "comments",
+			src:   "a -- x\n# y\n/* z */b",
+			kinds: []TokenKind{Word, Whitespace, Comment, Whitespace, Comment, Whitespace, Comment, Word},
+			texts: []string{"a", " ", "-- x", "\n", "# y", "\n", "/* z */", "b"},
+		},
+		{
+			name:  "double dash without space is an operator",
+			src:   "1--1",
+			kinds: []TokenKind{Number, Punct, Punct, Number},
+			texts: []string{"1", "-", "-", "1"},
+		},
+		{
+			name:  "variables and operators",
+			src:   "@@sql_mode<=>@x",
+			kinds: []TokenKind{Variable, Punct, Variable},
+			texts: []string{"@@sql_mode", "<=>", "@x"},
+		},
+		{
+			name:  "charset introducer",
+			src:   "_utf8mb4'abc' AS x",
+			kinds: []TokenKind{String, Whitespace, Word, Whitespace, Word},
+			texts: []string{"_utf8mb4'abc'", " ", "AS", " ", "x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Tokenize(tt.src)
+			if err != nil {
+				t.Fatalf("Tokenize() error = %v", err)
+			}
+			if len(tokens) != len(tt.kinds) {
+				t.Fatalf("Tokenize() returned %d tokens, want %d: %v", len(tokens), len(tt.kinds), tokens)
+			}
+			var rebuilt strings.Builder
+			for i, tok := range tokens {
+				if tok.Kind != tt.kinds[i] || tok.Text != tt.texts[i] {
+					t.Errorf("token %d = %v %q, want %v %q", i, tok.Kind, tok.Text, tt.kinds[i], tt.texts[i])
+				}
+				rebuilt.WriteString(tok.Text)
+			}
+			if rebuilt.String() != tt.src {
+				t.Errorf("tokens do not reproduce the source: %q", rebuilt.String())
+			}
+		})
+	}
+}
+
+func TestTokenValue(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"`a``b`", "a`b"},
+		{`'it''s'`, "it's"},
+		{`'a\nb'`, "a\nb"},
+		{"_latin1'x'", "x"},
+	}
+	for _, tt := range tests {
+		tokens, err := Tokenize(tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tokens[0].Value(); got != tt.want {
+			t.Errorf("Value(%s) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
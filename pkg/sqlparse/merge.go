@@ -0,0 +1,93 @@
+package sqlparse
+
+import "strings"
+
+// Aggregate functions, which keep a view from being merged
+var aggregates = map[string]bool{
+	"AVG": true, "BIT_AND": true, "BIT_OR": true, "BIT_XOR": true, "COUNT": true, "GROUP_CONCAT": true,
+	"JSON_ARRAYAGG": true, "JSON_OBJECTAGG": true, "MAX": true, "MIN": true, "STD": true, "STDDEV": true,
+	"STDDEV_POP": true, "STDDEV_SAMP": true, "SUM": true, "VAR_POP": true, "VAR_SAMP": true, "VARIANCE": true,
+}
+
+// MergeBlockers returns the constructs of the view's query that keep MySQL
+// from using ALGORITHM=MERGE, in order of appearance: aggregate and window
+// functions, DISTINCT, GROUP BY, HAVING, LIMIT, UNION, subqueries in the
+// select list, assignments to user variables, and selecting only literal
+// values. MySQL stores such a view with ALGORITHM=UNDEFINED and a warning
+// when MERGE is requested. An empty result means MERGE can be used.
+func (v *CreateView) MergeBlockers() []string {
+	tokens, err := significantTokens(v.Select)
+	if err != nil {
+		return nil
+	}
+
+	var blockers []string
+	seen := make(map[string]bool)
+	block := func(reason string) {
+		if !seen[reason] {
+			seen[reason] = true
+			blockers = append(blockers, reason)
+		}
+	}
+
+	depth := 0
+	subquery := 0         // depth of the subquery being skipped, or 0
+	inSelectList := false // between the outer SELECT and its FROM
+	hasTable := false
+	for i, t := range tokens {
+		next := Token{}
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+		switch {
+		case t.IsPunct("("):
+			depth++
+			if subquery == 0 && (next.Is("SELECT") || next.Is("WITH")) {
+				subquery = depth
+				if inSelectList {
+					block("subquery in the select list")
+				}
+			}
+			continue
+		case t.IsPunct(")"):
+			if subquery == depth {
+				subquery = 0
+			}
+			depth--
+			continue
+		case subquery > 0:
+			continue
+		}
+
+		// Functions count anywhere in the outer query, clauses only at its top level
+		switch {
+		case t.Is("OVER") && (next.IsPunct("(") || next.Kind == Word || next.Kind == QuotedIdentifier):
+			block("window function")
+		case t.Kind == Word && aggregates[strings.ToUpper(t.Text)] && next.IsPunct("("):
+			block("aggregate function " + strings.ToUpper(t.Text))
+		case t.IsPunct(":="):
+			block("assignment to a user variable")
+		case depth > 0:
+		case t.Is("SELECT"):
+			inSelectList = true
+			if next.Is("DISTINCT") || next.Is("DISTINCTROW") {
+				block("DISTINCT")
+			}
+		case t.Is("FROM"):
+			inSelectList = false
+			if !next.Is("DUAL") {
+				hasTable = true
+			}
+		case t.Is("GROUP") && next.Is("BY"):
+			block("GROUP BY")
+		case t.Is("HAVING"), t.Is("LIMIT"):
+			block(strings.ToUpper(t.Text))
+		case t.Is("UNION"), t.Is("INTERSECT"), t.Is("EXCEPT"):
+			block("UNION")
+		}
+	}
+	if !hasTable {
+		block("no table, only literal values")
+	}
+	return blockers
+}
@@ -0,0 +1,43 @@
+package sqlparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeBlockers(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "select `o`.`id` AS `id`, upper(`o`.`note`) AS `note` from (`orders` `o` join `customers` `c` on((`c`.`id` = `o`.`customer_id`))) where `o`.`id` in (select `id` from `open_orders` group by `id`)"},
+		{query: "select extract(year from `o`.`created`) AS `y` from `orders` `o`"},
+		{
+			query: "select distinct `o`.`customer_id` AS `customer_id`, count(0) AS `n` from `orders` `o` group by `o`.`customer_id` having (count(0) > 1) limit 10",
+			want:  []string{"DISTINCT", "aggregate function COUNT", "GROUP BY", "HAVING", "LIMIT"},
+		},
+		{
+			query: "select `a`.`id` AS `id` from `a` union all select `b`.`id` AS `id` from `b`",
+			want:  []string{"UNION"},
+		},
+		{
+			query: "select `o`.`id` AS `id`, coalesce((select max(`p`.`paid`) from `payments` `p`), 0) AS `paid` from `orders` `o`",
+			want:  []string{"subquery in the select list"},
+		},
+		{
+			query: "select `o`.`id` AS `id`, row_number() OVER (ORDER BY `o`.`id` )  AS `n`, (@x := 1) AS `x` from `orders` `o`",
+			want:  []string{"window function", "assignment to a user variable"},
+		},
+		{
+			query: "select 1 AS `one` from dual",
+			want:  []string{"no table, only literal values"},
+		},
+	}
+	for _, tt := range tests {
+		v := &CreateView{Select: tt.query}
+		got := v.MergeBlockers()
+		if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+			t.Errorf("MergeBlockers(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package sqlparse
+
+import (
+	"sort"
+	"strings"
+)
+
+// Words that may stand between INSERT or REPLACE and INTO
+var insertModifiers = map[string]bool{
+	"INSERT": true, "REPLACE": true, "IGNORE": true, "LOW_PRIORITY": true, "DELAYED": true, "HIGH_PRIORITY": true,
+}
+
+// Words that end the table references of a FROM clause or UPDATE
+var tableListEnd = map[string]bool{
+	"WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true, "WINDOW": true, "UNION": true,
+	"INTERSECT": true, "EXCEPT": true, "INTO": true, "FOR": true, "LOCK": true, "SET": true, "SELECT": true,
+	"VALUES": true, "RETURNING": true,
+}
+
+// ReferencedTables returns the tables a statement or stored program body
+// reads or writes, in order of first appearance: the table references of
+// FROM clauses, joins and UPDATE, including comma-separated lists and
+// parenthesized joins, and the names following INSERT INTO and TABLE.
+// Qualified names are returned as schema.table with the quoting removed.
+// Derived tables, table functions, SELECT ... INTO variables and ON
+// DUPLICATE KEY UPDATE are not table references and are left out, but the
+// tables of subqueries are included.
+func ReferencedTables(stmt string) ([]string, error) {
+	tokens, err := significantTokens(stmt)
+	if err != nil {
+		return nil, err
+	}
+	isName := func(i int) bool {
+		return i < len(tokens) && (tokens[i].Kind == Word || tokens[i].Kind == QuotedIdentifier)
+	}
+	prevIs := func(i int, words ...string) bool {
+		if i == 0 {
+			return false
+		}
+		for _, w := range words {
+			if tokens[i-1].Is(w) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Tables are found clause by clause, so keep where each first appears
+	var tables []string
+	first := make(map[string]int)
+	// name returns the possibly qualified name at i and the index after it
+	name := func(i int) (string, int) {
+		if !isName(i) {
+			return "", i
+		}
+		if i+2 < len(tokens) && tokens[i+1].IsPunct(".") && isName(i+2) {
+			return tokens[i].Value() + "." + tokens[i+2].Value(), i + 3
+		}
+		return tokens[i].Value(), i + 1
+	}
+	add := func(name string, at int) {
+		if name == "" || strings.EqualFold(name, "dual") {
+			return
+		}
+		key := strings.ToLower(name)
+		if i, ok := first[key]; !ok {
+			tables = append(tables, name)
+			first[key] = at
+		} else if at < i {
+			first[key] = at
+		}
+	}
+
+	// addList records the table references starting at i, up to the end of
+	// the clause. Parentheses around joins are stepped into; other
+	// parentheses, such as derived tables and ON conditions, are skipped,
+	// since the loop below finds the tables of subqueries on its own.
+	addList := func(i int) {
+		var groups []bool // for each open parenthesis, whether it groups joins
+		expect := true    // whether a table reference comes next
+		for i < len(tokens) {
+			t := tokens[i]
+			inGroup := len(groups) == 0 || groups[len(groups)-1]
+			switch {
+			case t.IsPunct("("):
+				group := expect && !(i+1 < len(tokens) && (tokens[i+1].Is("SELECT") || tokens[i+1].Is("WITH") ||
+					tokens[i+1].Is("VALUES") || tokens[i+1].Is("TABLE")))
+				if !group {
+					expect = false
+				}
+				groups = append(groups, group)
+			case t.IsPunct(")"):
+				if len(groups) == 0 {
+					return
+				}
+				groups = groups[:len(groups)-1]
+			case !inGroup:
+			case t.IsPunct(";"), len(groups) == 0 && t.Kind == Word && tableListEnd[strings.ToUpper(t.Text)]:
+				return
+			case len(groups) == 0 && t.Is("ON") && i+1 < len(tokens) && tokens[i+1].Is("DUPLICATE"):
+				return
+			case t.IsPunct(","), t.Is("JOIN"), t.Is("STRAIGHT_JOIN"):
+				expect = true
+			case expect && (t.Is("LOW_PRIORITY") || t.Is("IGNORE") || t.Is("LATERAL")):
+			case expect:
+				expect = false
+				table, next := name(i)
+				// A name followed by a parenthesis is a table function such as JSON_TABLE
+				if next == i || next < len(tokens) && tokens[next].IsPunct("(") {
+					break
+				}
+				add(table, i)
+				i = next
+				continue
+			}
+			i++
+		}
+	}
+
+	// calls holds, for each open parenthesis, whether it holds the arguments
+	// of a function, where FROM is part of e.g. EXTRACT(YEAR FROM d)
+	var calls []bool
+	for i, t := range tokens {
+		switch {
+		case t.IsPunct("("):
+			calls = append(calls, i > 0 && tokens[i-1].Kind == Word && !parenKeywords[strings.ToUpper(tokens[i-1].Text)] &&
+				!(i+1 < len(tokens) && (tokens[i+1].Is("SELECT") || tokens[i+1].Is("WITH"))))
+		case t.IsPunct(")"):
+			if len(calls) > 0 {
+				calls = calls[:len(calls)-1]
+			}
+		case t.Is("FROM"):
+			if len(calls) == 0 || !calls[len(calls)-1] {
+				addList(i + 1)
+			}
+		case t.Is("JOIN"):
+			addList(i + 1)
+		case t.Is("STRAIGHT_JOIN"):
+			// SELECT STRAIGHT_JOIN is a modifier, not a join
+			if i > 0 && !tokens[i-1].Is("SELECT") && !selectModifiers[strings.ToUpper(tokens[i-1].Text)] {
+				addList(i + 1)
+			}
+		case t.Is("UPDATE"):
+			if !prevIs(i, "KEY", "FOR") {
+				addList(i + 1)
+			}
+		case t.Is("INTO"):
+			if i > 0 && insertModifiers[strings.ToUpper(tokens[i-1].Text)] {
+				table, _ := name(i + 1)
+				add(table, i+1)
+			}
+		case t.Is("TABLE"):
+			table, _ := name(i + 1)
+			add(table, i+1)
+		}
+	}
+	sort.SliceStable(tables, func(i, j int) bool { return first[strings.ToLower(tables[i])] < first[strings.ToLower(tables[j])] })
+	return tables, nil
+}
+
+// Keywords that may be followed by a parenthesis without calling a function
+var parenKeywords = map[string]bool{
+	"VALUES": true, "VALUE": true, "IN": true, "EXISTS": true, "AS": true, "ON": true, "USING": true,
+	"AND": true, "OR": true, "NOT": true, "WHERE": true, "SELECT": true, "RETURN": true, "KEY": true, "INDEX": true,
+}
+
+// ReferencedRoutines returns the procedures a statement or stored program
+// body calls with CALL, and the names it calls like functions, in order of
+// first appearance. Qualified names are returned as schema.name with the
+// quoting removed. Built-in functions are not told apart from stored ones,
+// so callers should look the function names up.
+func ReferencedRoutines(stmt string) (procedures, functions []string, err error) {
+	tokens, err := significantTokens(stmt)
+	if err != nil {
+		return nil, nil, err
+	}
+	isName := func(i int) bool {
+		return i >= 0 && i < len(tokens) && (tokens[i].Kind == Word || tokens[i].Kind == QuotedIdentifier)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < len(tokens); i++ {
+		// Skip the column of a table qualified name such as t.c
+		if !isName(i) || (i > 0 && tokens[i-1].IsPunct(".")) {
+			continue
+		}
+		start := i
+		name := tokens[i].Value()
+		if i+2 < len(tokens) && tokens[i+1].IsPunct(".") && isName(i+2) {
+			name += "." + tokens[i+2].Value()
+			i += 2
+		}
+		called := start > 0 && tokens[start-1].Is("CALL")
+		// INSERT INTO t (a, b) names columns, not arguments
+		invoked := i+1 < len(tokens) && tokens[i+1].IsPunct("(") && !(start > 0 && tokens[start-1].Is("INTO"))
+		key := strings.ToLower(name)
+		switch {
+		case called && !seen["p:"+key]:
+			seen["p:"+key] = true
+			procedures = append(procedures, name)
+		case !called && invoked && !seen["f:"+key] && !(tokens[start].Kind == Word && parenKeywords[strings.ToUpper(name)]):
+			seen["f:"+key] = true
+			functions = append(functions, name)
+		}
+	}
+	return procedures, functions, nil
+}
+
+// significantTokens tokenizes stmt without whitespace and comments
+func significantTokens(stmt string) ([]Token, error) {
+	all, err := Tokenize(stmt)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []Token
+	for _, t := range all {
+		if t.Significant() {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,121 @@
+package sqlparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReferencedTables(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want []string
+	}{
+		{
+			stmt: "BEGIN INSERT INTO audit.log (id) VALUES (NEW.id); UPDATE `totals` SET n = n + 1; END",
+			want: []string{"audit.log", "totals"},
+		},
+		{
+			stmt: "BEGIN SELECT c INTO v FROM `app`.`config` JOIN limits l ON 1 FOR UPDATE; DELETE FROM queue WHERE id = OLD.id; END",
+			want: []string{"app.config", "limits", "queue"},
+		},
+		{
+			stmt: "INSERT INTO t SELECT * FROM (SELECT 1) AS d ON DUPLICATE KEY UPDATE x = 1",
+			want: []string{"t"},
+		},
+		{
+			stmt: "SET NEW.total = (SELECT 1 FROM dual) -- FROM comment_table",
+		},
+		// Trigger bodies keep joins as written
+		{
+			stmt: "INSERT INTO audit.log (id) SELECT o.id FROM (orders o JOIN customers c ON c.id = o.customer_id) WHERE o.id = NEW.id",
+			want: []string{"audit.log", "orders", "customers"},
+		},
+		{
+			stmt: "BEGIN\n  DELETE FROM queue WHERE id IN (SELECT q.id FROM queue q, archive a WHERE a.id = q.id);\nEND",
+			want: []string{"queue", "archive"},
+		},
+		// VIEW_DEFINITION as MySQL stores it
+		{
+			stmt: "select `t`.`id` AS `id`,`u`.`name` AS `name` from (`t` left join `u` on((`u`.`id` = `t`.`user_id`)))",
+			want: []string{"t", "u"},
+		},
+		{
+			stmt: "select `a`.`id` AS `id` from ((`app`.`a` join `app`.`b` on((`b`.`id` = `a`.`id`))) join `app`.`c` on((`c`.`id` = `a`.`id`)))",
+			want: []string{"app.a", "app.b", "app.c"},
+		},
+		{
+			stmt: "select `o`.`id` AS `id` from ((`app`.`orders` `o` join `app`.`customers` `c`) join `app`.`regions` `r`) where ((`c`.`id` = `o`.`customer_id`) and (`r`.`id` = `c`.`region_id`))",
+			want: []string{"app.orders", "app.customers", "app.regions"},
+		},
+		{
+			stmt: "select `d`.`n` AS `n`,`s`.`name` AS `name` from ((select count(0) AS `n`,`app`.`orders`.`shop_id` AS `shop_id` from `app`.`orders` group by `app`.`orders`.`shop_id`) `d` join `app`.`shops` `s` on((`s`.`id` = `d`.`shop_id`)))",
+			want: []string{"app.orders", "app.shops"},
+		},
+		{
+			stmt: "select `o`.`id` AS `id` from `app`.`orders` `o` where `o`.`id` in (select `p`.`order_id` from `app`.`payments` `p`)",
+			want: []string{"app.orders", "app.payments"},
+		},
+		{
+			stmt: "select `j`.`x` AS `x` from (`app`.`docs` `d` join json_table(`d`.`body`, '$[*]' columns (`x` int path '$.x')) `j`)",
+			want: []string{"app.docs"},
+		},
+		{
+			stmt: "select extract(year from `o`.`created`) AS `y`,trim(leading '0' from `o`.`code`) AS `code` from `app`.`orders` `o`",
+			want: []string{"app.orders"},
+		},
+		// Comma joins as written, e.g. in ALTER VIEW or a routine body
+		{
+			stmt: "SELECT o.id FROM orders o, customers AS c, (SELECT id FROM regions) r, `app`.`shops` WHERE o.customer_id = c.id",
+			want: []string{"orders", "customers", "regions", "app.shops"},
+		},
+		{
+			stmt: "SELECT STRAIGHT_JOIN a.id FROM a STRAIGHT_JOIN b ON b.id = a.id FOR UPDATE",
+			want: []string{"a", "b"},
+		},
+		{
+			stmt: "UPDATE orders o, totals t SET t.n = t.n + 1 WHERE t.id = o.id",
+			want: []string{"orders", "totals"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ReferencedTables(tt.stmt)
+		if err != nil {
+			t.Errorf("ReferencedTables(%q) error: %v", tt.stmt, err)
+			continue
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ReferencedTables(%q) = %v, want %v", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestReferencedRoutines(t *testing.T) {
+	tests := []struct {
+		stmt       string
+		procedures []string
+		functions  []string
+	}{
+		{
+			stmt:       "BEGIN CALL `audit`.`log_change`(NEW.id); CALL refresh_totals; SET @x = fmt_money(NEW.total); END",
+			procedures: []string{"audit.log_change", "refresh_totals"},
+			functions:  []string{"fmt_money"},
+		},
+		{
+			stmt:      "select `app`.`tax`(`o`.`total`) AS `t`, count(0) AS `n` from `app`.`orders` `o`",
+			functions: []string{"app.tax", "count"},
+		},
+		{
+			stmt: "INSERT INTO totals (id, n) VALUES (1, 2) -- CALL commented_out()",
+		},
+	}
+	for _, tt := range tests {
+		procedures, functions, err := ReferencedRoutines(tt.stmt)
+		if err != nil {
+			t.Errorf("ReferencedRoutines(%q) error: %v", tt.stmt, err)
+			continue
+		}
+		if strings.Join(procedures, ",") != strings.Join(tt.procedures, ",") || strings.Join(functions, ",") != strings.Join(tt.functions, ",") {
+			t.Errorf("ReferencedRoutines(%q) = %v, %v, want %v, %v", tt.stmt, procedures, functions, tt.procedures, tt.functions)
+		}
+	}
+}
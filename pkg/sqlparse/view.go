@@ -0,0 +1,340 @@
+package sqlparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CreateView is a parsed CREATE VIEW or ALTER VIEW statement
+type CreateView struct {
+	Verb        string   // CREATE, CREATE OR REPLACE or ALTER
+	Algorithm   string   // UNDEFINED, MERGE, TEMPTABLE, or empty if not given
+	DefinerUser string   // empty if no DEFINER clause was given
+	DefinerHost string   // empty for CURRENT_USER
+	Security    string   // DEFINER, INVOKER, or empty if not given
+	Schema      string   // empty if the name was not qualified
+	Name        string   // view name, unquoted
+	Columns     []string // explicit column list, unquoted
+	Select      string   // the query after AS, exactly as written
+	CheckOption string   // CASCADED, LOCAL, or empty for none
+}
+
+// Definer renders the definer the way SHOW CREATE VIEW does, e.g. `app`@`%`
+func (v *CreateView) Definer() string {
+	if v.DefinerUser == "" {
+		return ""
+	}
+	if v.DefinerHost == "" {
+		return v.DefinerUser
+	}
+	return QuoteIdentifier(v.DefinerUser) + "@" + QuoteIdentifier(v.DefinerHost)
+}
+
+// QualifiedName returns the backtick-quoted, schema-qualified view name
+func (v *CreateView) QualifiedName() string {
+	if v.Schema == "" {
+		return QuoteIdentifier(v.Name)
+	}
+	return QuoteIdentifier(v.Schema) + "." + QuoteIdentifier(v.Name)
+}
+
+// AlterStatement renders the view as ALTER VIEW in go-pvt's layout
+func (v *CreateView) AlterStatement() string {
+	return v.render("ALTER")
+}
+
+// CreateOrReplaceStatement renders the view as CREATE OR REPLACE VIEW
+func (v *CreateView) CreateOrReplaceStatement() string {
+	return v.render("CREATE OR REPLACE")
+}
+
+// render lays the clauses out one per line, ending with a semicolon
+func (v *CreateView) render(verb string) string {
+	var b strings.Builder
+	b.WriteString(verb + " ")
+	if v.Algorithm != "" {
+		b.WriteString("\n    ALGORITHM = " + v.Algorithm)
+	}
+	if definer := v.Definer(); definer != "" {
+		b.WriteString("\n    DEFINER=" + definer)
+	}
+	if v.Security != "" {
+		b.WriteString("\n    SQL SECURITY " + v.Security)
+	}
+	b.WriteString("\n    VIEW " + v.QualifiedName())
+	if len(v.Columns) > 0 {
+		quoted := make([]string, len(v.Columns))
+		for i, col := range v.Columns {
+			quoted[i] = QuoteIdentifier(col)
+		}
+		b.WriteString(" (" + strings.Join(quoted, ",") + ")")
+	}
+	b.WriteString(" AS " + v.Select)
+	if v.CheckOption != "" {
+		b.WriteString(" WITH " + v.CheckOption + " CHECK OPTION")
+	}
+	b.WriteString(";")
+	return b.String()
+}
+
+// viewParser walks the significant tokens of a statement
+type viewParser struct {
+	src    string
+	tokens []Token
+	pos    int
+}
+
+func (p *viewParser) peek() Token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return Token{Kind: Punct, Start: len(p.src), End: len(p.src)}
+}
+
+func (p *viewParser) next() Token {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *viewParser) atEnd() bool {
+	return p.pos >= len(p.tokens)
+}
+
+// accept consumes the keyword if it is next
+func (p *viewParser) accept(keyword string) bool {
+	if p.peek().Is(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *viewParser) expect(keyword string) error {
+	if !p.accept(keyword) {
+		return p.errorf("expected %s", keyword)
+	}
+	return nil
+}
+
+func (p *viewParser) expectPunct(punct string) error {
+	if !p.peek().IsPunct(punct) {
+		return p.errorf("expected '%s'", punct)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *viewParser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	near := t.Text
+	if p.atEnd() {
+		near = "end of statement"
+	}
+	return fmt.Errorf("%s near %q at offset %d", fmt.Sprintf(format, args...), near, t.Start)
+}
+
+// identifier reads a bare or backtick-quoted identifier
+func (p *viewParser) identifier() (string, error) {
+	t := p.peek()
+	if t.Kind != Word && t.Kind != QuotedIdentifier {
+		return "", p.errorf("expected identifier")
+	}
+	p.pos++
+	return t.Value(), nil
+}
+
+// userPart reads the user or host half of an account name
+func (p *viewParser) userPart() (string, error) {
+	t := p.peek()
+	switch t.Kind {
+	case Word, QuotedIdentifier, String:
+		p.pos++
+		return t.Value(), nil
+	case Punct:
+		// Unquoted wildcard hosts such as % are lexed as punctuation
+		if t.Text == "%" {
+			p.pos++
+			return t.Text, nil
+		}
+	}
+	return "", p.errorf("expected user name")
+}
+
+// ParseCreateView parses a CREATE [OR REPLACE] VIEW or ALTER VIEW statement.
+// Comments and a trailing semicolon are allowed anywhere whitespace is.
+func ParseCreateView(stmt string) (*CreateView, error) {
+	all, err := Tokenize(stmt)
+	if err != nil {
+		return nil, err
+	}
+	p := &viewParser{src: stmt}
+	for _, t := range all {
+		if t.Significant() {
+			p.tokens = append(p.tokens, t)
+		}
+	}
+	// Drop trailing semicolons
+	for len(p.tokens) > 0 && p.tokens[len(p.tokens)-1].IsPunct(";") {
+		p.tokens = p.tokens[:len(p.tokens)-1]
+	}
+
+	v := &CreateView{}
+	switch {
+	case p.accept("CREATE"):
+		v.Verb = "CREATE"
+		if p.accept("OR") {
+			if err := p.expect("REPLACE"); err != nil {
+				return nil, err
+			}
+			v.Verb = "CREATE OR REPLACE"
+		}
+	case p.accept("ALTER"):
+		v.Verb = "ALTER"
+	default:
+		return nil, p.errorf("expected CREATE or ALTER")
+	}
+
+	if p.accept("ALGORITHM") {
+		if err := p.expectPunct("="); err != nil {
+			return nil, err
+		}
+		algo := strings.ToUpper(p.next().Text)
+		if algo != "UNDEFINED" && algo != "MERGE" && algo != "TEMPTABLE" {
+			p.pos--
+			return nil, p.errorf("invalid algorithm")
+		}
+		v.Algorithm = algo
+	}
+
+	if p.accept("DEFINER") {
+		if err := p.expectPunct("="); err != nil {
+			return nil, err
+		}
+		if p.accept("CURRENT_USER") {
+			v.DefinerUser = "CURRENT_USER"
+			if p.peek().IsPunct("(") {
+				p.pos++
+				if err := p.expectPunct(")"); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			user, err := p.userPart()
+			if err != nil {
+				return nil, err
+			}
+			v.DefinerUser = user
+			v.DefinerHost = "%"
+			if p.peek().IsPunct("@") {
+				p.pos++
+				host, err := p.userPart()
+				if err != nil {
+					return nil, err
+				}
+				v.DefinerHost = host
+			} else if t := p.peek(); t.Kind == Variable && !strings.HasPrefix(t.Text, "@@") {
+				// Bare user@host is lexed as a word followed by a variable
+				p.pos++
+				v.DefinerHost = t.Text[1:]
+			}
+		}
+	}
+
+	if p.accept("SQL") {
+		if err := p.expect("SECURITY"); err != nil {
+			return nil, err
+		}
+		switch {
+		case p.accept("DEFINER"):
+			v.Security = "DEFINER"
+		case p.accept("INVOKER"):
+			v.Security = "INVOKER"
+		default:
+			return nil, p.errorf("expected DEFINER or INVOKER")
+		}
+	}
+
+	if err := p.expect("VIEW"); err != nil {
+		return nil, err
+	}
+
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().IsPunct(".") {
+		p.pos++
+		v.Schema = name
+		if name, err = p.identifier(); err != nil {
+			return nil, err
+		}
+	}
+	v.Name = name
+
+	if p.peek().IsPunct("(") {
+		p.pos++
+		for {
+			col, err := p.identifier()
+			if err != nil {
+				return nil, err
+			}
+			v.Columns = append(v.Columns, col)
+			if p.peek().IsPunct(",") {
+				p.pos++
+				continue
+			}
+			if err := p.expectPunct(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if err := p.expect("AS"); err != nil {
+		return nil, err
+	}
+	if p.atEnd() {
+		return nil, p.errorf("missing view query")
+	}
+	bodyStart := p.pos
+
+	// WITH [CASCADED | LOCAL] CHECK OPTION can only be the last clause
+	bodyEnd := len(p.tokens)
+	n := len(p.tokens)
+	if n-bodyStart >= 4 && p.tokens[n-1].Is("OPTION") && p.tokens[n-2].Is("CHECK") {
+		switch {
+		case p.tokens[n-3].Is("WITH"):
+			v.CheckOption = "CASCADED"
+			bodyEnd = n - 3
+		case (p.tokens[n-3].Is("CASCADED") || p.tokens[n-3].Is("LOCAL")) && p.tokens[n-4].Is("WITH"):
+			v.CheckOption = strings.ToUpper(p.tokens[n-3].Text)
+			bodyEnd = n - 4
+		}
+	}
+	if bodyEnd <= bodyStart {
+		return nil, p.errorf("missing view query")
+	}
+
+	// Parentheses must balance, otherwise the statement was cut off
+	depth := 0
+	for _, t := range p.tokens[bodyStart:bodyEnd] {
+		switch {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+		}
+		if depth < 0 {
+			break
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in view query")
+	}
+
+	v.Select = stmt[p.tokens[bodyStart].Start:p.tokens[bodyEnd-1].End]
+	return v, nil
+}
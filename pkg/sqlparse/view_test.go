@@ -0,0 +1,129 @@
+package sqlparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCreateView(t *testing.T) {
+	tests := []struct {
+		name string
+		stmt string
+		want CreateView
+	}{
+		{
+			name: "show create view output",
+			stmt: "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`localhost` SQL SECURITY DEFINER VIEW `my_view` AS select `my_table`.`col1` AS `col1` from `my_table` where (`my_table`.`col1` > 0)",
+			want: CreateView{
+				Verb: "CREATE", Algorithm: "UNDEFINED", DefinerUser: "root", DefinerHost: "localhost", Security: "DEFINER",
+				Name:   "my_view",
+				Select: "select `my_table`.`col1` AS `col1` from `my_table` where (`my_table`.`col1` > 0)",
+			},
+		},
+		{
+			name: "quoted identifiers containing spaces, backticks and AS",
+			stmt: "CREATE ALGORITHM=MERGE DEFINER=`app user`@`10.0.%` SQL SECURITY INVOKER VIEW `db`.`weird AS ``name``` AS select 1 AS `x`",
+			want: CreateView{
+				Verb: "CREATE", Algorithm: "MERGE", DefinerUser: "app user", DefinerHost: "10.0.%", Security: "INVOKER",
+				Schema: "db", Name: "weird AS `name`",
+				Select: "select 1 AS `x`",
+			},
+		},
+		{
+			name: "check option is split from the body",
+			stmt: "ALTER ALGORITHM = TEMPTABLE DEFINER=`a`@`%` SQL SECURITY DEFINER VIEW `v` AS select * from t where x > 1 WITH LOCAL CHECK OPTION;",
+			want: CreateView{
+				Verb: "ALTER", Algorithm: "TEMPTABLE", DefinerUser: "a", DefinerHost: "%", Security: "DEFINER",
+				Name: "v", Select: "select * from t where x > 1", CheckOption: "LOCAL",
+			},
+		},
+		{
+			name: "bare check option defaults to cascaded",
+			stmt: "CREATE VIEW v AS select a from t WITH CHECK OPTION",
+			want: CreateView{Verb: "CREATE", Name: "v", Select: "select a from t", CheckOption: "CASCADED"},
+		},
+		{
+			name: "string literals hide keywords",
+			stmt: "CREATE DEFINER='root'@'%' VIEW v AS select 'x AS y WITH CHECK OPTION' AS `s`, \"it''s\" AS q",
+			want: CreateView{
+				Verb: "CREATE", DefinerUser: "root", DefinerHost: "%", Name: "v",
+				Select: "select 'x AS y WITH CHECK OPTION' AS `s`, \"it''s\" AS q",
+			},
+		},
+		{
+			name: "comments are skipped",
+			stmt: "/* header */ CREATE OR REPLACE -- note\n VIEW v /* AS fake */ AS # trailing\n select 1 /* end */",
+			want: CreateView{Verb: "CREATE OR REPLACE", Name: "v", Select: "select 1"},
+		},
+		{
+			name: "column list and bare definer",
+			stmt: "CREATE DEFINER=root@localhost VIEW v (`a`, b) AS select 1, 2",
+			want: CreateView{
+				Verb: "CREATE", DefinerUser: "root", DefinerHost: "localhost", Name: "v",
+				Columns: []string{"a", "b"}, Select: "select 1, 2",
+			},
+		},
+		{
+			name: "current user definer",
+			stmt: "CREATE DEFINER=CURRENT_USER() VIEW v AS select now()",
+			want: CreateView{Verb: "CREATE", DefinerUser: "CURRENT_USER", Name: "v", Select: "select now()"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCreateView(tt.stmt)
+			if err != nil {
+				t.Fatalf("ParseCreateView() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseCreateView() =\n%#v\nwant\n%#v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCreateViewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		stmt string
+	}{
+		{"not a view", "CREATE TABLE t (a int)"},
+		{"missing body", "CREATE VIEW v AS"},
+		{"bad algorithm", "CREATE ALGORITHM=FAST VIEW v AS select 1"},
+		{"unterminated string", "CREATE VIEW v AS select 'abc"},
+		{"unbalanced parentheses", "CREATE VIEW v AS select (1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCreateView(tt.stmt); err == nil {
+				t.Errorf("ParseCreateView(%q) expected an error", tt.stmt)
+			}
+		})
+	}
+}
+
+func TestCreateViewRender(t *testing.T) {
+	v, err := ParseCreateView("CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v` (`a`) AS select 1 WITH CASCADED CHECK OPTION")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Schema = "db"
+	v.Algorithm = "MERGE"
+
+	want := "ALTER \n    ALGORITHM = MERGE\n    DEFINER=`root`@`%`\n    SQL SECURITY DEFINER\n    VIEW `db`.`v` (`a`) AS select 1 WITH CASCADED CHECK OPTION;"
+	if got := v.AlterStatement(); got != want {
+		t.Errorf("AlterStatement() =\n%s\nwant\n%s", got, want)
+	}
+
+	// The rendered statement must parse back to the same view
+	back, err := ParseCreateView(v.CreateOrReplaceStatement())
+	if err != nil {
+		t.Fatal(err)
+	}
+	back.Verb = v.Verb
+	if !reflect.DeepEqual(back, v) {
+		t.Errorf("round trip =\n%#v\nwant\n%#v", back, v)
+	}
+}
@@ -0,0 +1,79 @@
+package viewformat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// ReadViews reads views in any format view-formatter understands. A JSON
+// array or NDJSON stream, as written by go-pvt -algo -o json or -o ndjson,
+// is read with ReadExport; anything else is parsed as captured console
+// output with ParseViews, which logs each view it finds to logger.
+func ReadViews(r io.Reader, logger *slog.Logger) ([]ViewInfo, error) {
+	br := bufio.NewReader(r)
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			continue
+		}
+		if err := br.UnreadByte(); err != nil {
+			return nil, err
+		}
+		if c == '[' || c == '{' {
+			return ReadExport(br)
+		}
+		return ParseViews(br, logger)
+	}
+}
+
+// ReadExport reads the go-pvt view export, either a JSON array of view
+// records or one record per line. Records without a name or an ALTER
+// statement are rejected rather than turned into empty migrations.
+func ReadExport(r io.Reader) ([]ViewInfo, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var views []ViewInfo
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &views); err != nil {
+			return nil, fmt.Errorf("error reading view export: %v", err)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var view ViewInfo
+			err := dec.Decode(&view)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error reading view export record %d: %v", len(views)+1, err)
+			}
+			views = append(views, view)
+		}
+	}
+
+	for i, view := range views {
+		switch {
+		case view.ViewName == "":
+			return nil, fmt.Errorf("view export record %d has no name", i+1)
+		case view.Database == "":
+			return nil, fmt.Errorf("view export record %d (%s) has no schema", i+1, view.ViewName)
+		case view.AlterSQL == "":
+			return nil, fmt.Errorf("view export record %d (%s.%s) has no alter_statement", i+1, view.Database, view.ViewName)
+		}
+	}
+	return views, nil
+}
@@ -0,0 +1,76 @@
+package viewformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadViews(t *testing.T) {
+	const record = `{"host":"primary","schema":"app","name":"v1","definer":"app@%","algorithm":"UNDEFINED","new_algorithm":"MERGE","character_set_client":"utf8mb4","collation_connection":"utf8mb4_0900_ai_ci","create_statement":"CREATE VIEW v1 AS select 1","alter_statement":"ALTER ALGORITHM = MERGE VIEW v1 AS select 1;"}`
+
+	tests := []struct {
+		name     string
+		input    string
+		want     []string // schema.name of every view
+		hasError bool
+	}{
+		{
+			name:  "json array",
+			input: "[\n  " + record + ",\n  " + strings.Replace(record, `"v1"`, `"v2"`, 1) + "\n]\n",
+			want:  []string{"app.v1", "app.v2"},
+		},
+		{
+			name:  "ndjson",
+			input: record + "\n" + strings.Replace(record, `"v1"`, `"v2"`, 1) + "\n",
+			want:  []string{"app.v1", "app.v2"},
+		},
+		{
+			name:  "empty json array",
+			input: "[]\n",
+		},
+		{
+			name: "legacy text with colour codes and stray log lines",
+			input: "Connected to primary (db1): \x1b[32m✔\x1b[0m\n" +
+				"Source server: primary, Database: app\n\n" +
+				"Processing view: v1\n" +
+				"\x1b[32mCreate Statement\x1b[0m:\n" + strings.Repeat("-", 80) + "\nCREATE VIEW v1 AS select 1\n" + strings.Repeat("-", 80) + "\n" +
+				"\x1b[32mALTER VIEW Statement\x1b[0m:\n" + strings.Repeat("-", 80) + "\nALTER ALGORITHM = MERGE VIEW v1 AS select 1;\n" + strings.Repeat("-", 80) + "\n",
+			want: []string{"app.v1"},
+		},
+		{
+			name:     "record without alter statement",
+			input:    strings.Replace(record, `"alter_statement":"ALTER ALGORITHM = MERGE VIEW v1 AS select 1;"`, `"alter_statement":""`, 1),
+			hasError: true,
+		},
+		{
+			name:     "truncated json",
+			input:    "[" + record,
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			views, err := ReadViews(strings.NewReader(tt.input), nil)
+			if tt.hasError {
+				if err == nil {
+					t.Fatalf("ReadViews() = %d views, want error", len(views))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadViews() error: %v", err)
+			}
+			var got []string
+			for _, view := range views {
+				if view.AlterSQL == "" {
+					t.Errorf("view %s has no AlterSQL", view.ViewName)
+				}
+				got = append(got, view.Database+"."+view.ViewName)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ReadViews() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package viewformat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// DefaultDescription is the description template used when none is given
+const DefaultDescription = "alter_view_{schema}_{name}"
+
+// Migration is a view with the Flyway version and description of its files
+type Migration struct {
+	View        ViewInfo
+	Version     Version
+	Description string
+	Up          string // the formatted ALTER VIEW
+	Down        string // CREATE OR REPLACE VIEW restoring the original definition
+}
+
+// VersionedFile returns the file name of the migration, V<version>__<description>.sql
+func (m Migration) VersionedFile() string {
+	return fmt.Sprintf("V%s__%s.sql", m.Version, m.Description)
+}
+
+// UndoFile returns the file name of the undo migration, U<version>__<description>.sql
+func (m Migration) UndoFile() string {
+	return fmt.Sprintf("U%s__%s.sql", m.Version, m.Description)
+}
+
+// descriptionChars matches what Flyway descriptions should not contain
+var descriptionChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// Double underscores separate the version from the description
+var doubleUnderscores = regexp.MustCompile(`__+`)
+
+// Describe expands a description template. {schema}, {name}, {algorithm} and
+// {host} are replaced with the view's values; the result is reduced to
+// letters, digits and underscores, which Flyway shows as spaces.
+func Describe(template string, view ViewInfo) string {
+	algorithm := view.NewAlgorithm
+	if algorithm == "" {
+		if parsed, err := sqlparse.ParseCreateView(view.AlterSQL); err == nil {
+			algorithm = parsed.Algorithm
+		}
+	}
+	description := strings.NewReplacer(
+		"{schema}", view.Database,
+		"{name}", view.ViewName,
+		"{algorithm}", strings.ToLower(algorithm),
+		"{host}", view.Server,
+	).Replace(template)
+	description = descriptionChars.ReplaceAllString(description, "_")
+	description = doubleUnderscores.ReplaceAllString(description, "_")
+	return strings.Trim(description, "_")
+}
+
+// Problem is a view PlanMigrations left out, and why
+type Problem struct {
+	View    ViewInfo
+	Skipped bool // the view needs no migration; otherwise it failed
+	Reason  string
+}
+
+// PlanMigrations orders views by their dependencies and gives each one the
+// next version from versioner, a description from template, the ALTER VIEW
+// laid out with format, and an undo script verified to restore the original
+// definition. Views that need no migration, or whose migration cannot be
+// built, are returned as problems instead; the error is reserved for
+// problems with the batch as a whole, such as a dependency cycle.
+func PlanMigrations(views []ViewInfo, versioner *Versioner, template string, format sqlparse.FormatOptions) ([]Migration, []Problem, error) {
+	var problems []Problem
+	planned := make(map[string]Migration)
+	seen := make(map[string]bool)
+	var accepted []ViewInfo
+	for _, view := range views {
+		key := viewKey(view.Database, view.ViewName)
+		switch {
+		case seen[key]:
+			problems = append(problems, Problem{View: view, Skipped: true, Reason: "duplicate of an earlier record"})
+			continue
+		case view.Algorithm != "" && strings.EqualFold(view.Algorithm, view.NewAlgorithm):
+			problems = append(problems, Problem{View: view, Skipped: true, Reason: "already uses ALGORITHM " + strings.ToUpper(view.Algorithm)})
+			continue
+		}
+		seen[key] = true
+		m, err := planMigration(view, template, format)
+		if err != nil {
+			problems = append(problems, Problem{View: view, Reason: err.Error()})
+			continue
+		}
+		planned[key] = m
+		accepted = append(accepted, view)
+	}
+
+	ordered, err := OrderByDependencies(accepted)
+	if err != nil {
+		return nil, problems, err
+	}
+	migrations := make([]Migration, len(ordered))
+	for i, view := range ordered {
+		migrations[i] = planned[viewKey(view.Database, view.ViewName)]
+		migrations[i].Version = versioner.Next()
+	}
+	return migrations, problems, nil
+}
+
+// planMigration builds the migration of one view, without a version
+func planMigration(view ViewInfo, template string, format sqlparse.FormatOptions) (Migration, error) {
+	// An empty migration would silently do nothing, so refuse to plan one
+	if strings.TrimSpace(view.AlterSQL) == "" {
+		return Migration{}, fmt.Errorf("no ALTER statement found")
+	}
+	description := Describe(template, view)
+	if description == "" {
+		return Migration{}, fmt.Errorf("description template %q is empty", template)
+	}
+	// The undo script must recreate the view exactly as it was captured
+	down, err := UndoSQL(view)
+	if err != nil {
+		return Migration{}, err
+	}
+	if err := VerifyUndo(view, down); err != nil {
+		return Migration{}, err
+	}
+	up, err := UpSQL(view, format)
+	if err != nil {
+		return Migration{}, err
+	}
+	return Migration{View: view, Description: description, Up: up, Down: down}, nil
+}
+
+// UpSQL returns the formatted statement that applies the change
+func UpSQL(view ViewInfo, opts sqlparse.FormatOptions) (string, error) {
+	// Format the ALTER SQL for better readability
+	return FormatSQL(view.AlterSQL, opts)
+}
+
+// FormatUFile renders the undo script of a migration
+func FormatUFile(m Migration) string {
+	return fmt.Sprintf(`-- Flyway Undo Script (Rollback)
+-- Database: %s
+-- View: %s
+-- Restores the original definition using CREATE OR REPLACE
+
+%s
+`, m.View.Database, m.View.ViewName, m.Down)
+}
+
+// FormatVFile renders the migration script of a migration
+func FormatVFile(m Migration) string {
+	return fmt.Sprintf(`-- Flyway Migration Script
+-- Database: %s
+-- View: %s
+
+%s
+`, m.View.Database, m.View.ViewName, m.Up)
+}
+
+// FormatSQL lays out a statement with sqlparse.Format, ending it with a semicolon
+func FormatSQL(sql string, opts sqlparse.FormatOptions) (string, error) {
+	if strings.TrimSpace(sql) == "" {
+		return "", fmt.Errorf("no SQL content found")
+	}
+	formatted, err := sqlparse.Format(sql, opts)
+	if err != nil {
+		return "", fmt.Errorf("could not format statement: %v", err)
+	}
+
+	// Ensure the statement ends with a semicolon
+	if !strings.HasSuffix(formatted, ";") {
+		formatted += ";"
+	}
+	return formatted, nil
+}
@@ -0,0 +1,269 @@
+package viewformat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+func TestVersioner(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"V1__init.sql", "V1_9__x.sql", "app/V1.10__y.sql", "app/U1.10__y.sql", "R__repeatable.sql", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		scheme string
+		start  string
+		dir    string
+		want   []string
+	}{
+		{scheme: VersionTimestamp, want: []string{"20240305143000.1", "20240305143000.2"}},
+		{scheme: VersionSequential, start: "7", want: []string{"7", "8", "9"}},
+		{scheme: VersionSequential, start: "2_1", want: []string{"2.1", "2.2"}},
+		{scheme: VersionScan, start: "1", dir: dir, want: []string{"1.11", "1.12"}},
+		{scheme: VersionScan, start: "100", dir: filepath.Join(dir, "missing"), want: []string{"100", "101"}},
+	}
+	for _, tt := range tests {
+		vr, err := NewVersioner(tt.scheme, tt.start, tt.dir, now)
+		if err != nil {
+			t.Errorf("NewVersioner(%s, %q) error: %v", tt.scheme, tt.start, err)
+			continue
+		}
+		var got []string
+		for range tt.want {
+			got = append(got, vr.Next().String())
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s from %q: got %v, want %v", tt.scheme, tt.start, got, tt.want)
+		}
+	}
+
+	if _, err := NewVersioner("semver", "1", "", now); err == nil {
+		t.Error("NewVersioner accepted an unknown scheme")
+	}
+	if _, err := NewVersioner(VersionSequential, "1.a", "", now); err == nil {
+		t.Error("NewVersioner accepted an invalid start version")
+	}
+}
+
+func TestIntegerTimestampVersioner(t *testing.T) {
+	now := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		files []string
+		want  []string
+	}{
+		{nil, []string{"20240305143000", "20240305143001"}},
+		{[]string{"1_init.up.sql", "1_init.down.sql"}, []string{"20240305143000", "20240305143001"}},
+		// An earlier run within the same few seconds
+		{[]string{"20240305143000_a.up.sql", "20240305143002_c.up.sql"}, []string{"20240305143003", "20240305143004"}},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for _, name := range tt.files {
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		vr, err := NewIntegerTimestampVersioner(dir, now)
+		if err != nil {
+			t.Errorf("NewIntegerTimestampVersioner(%v) error: %v", tt.files, err)
+			continue
+		}
+		got := []string{vr.Next().String(), vr.Next().String()}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("NewIntegerTimestampVersioner(%v): got %v, want %v", tt.files, got, tt.want)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	view := ViewInfo{Server: "db-1.example.com", Database: "app", ViewName: "rpt-daily", AlterSQL: "ALTER ALGORITHM = MERGE VIEW `rpt-daily` AS select 1;"}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{DefaultDescription, "alter_view_app_rpt_daily"},
+		{"{name} to {algorithm}", "rpt_daily_to_merge"},
+		{"{host}__{name}", "db_1_example_com_rpt_daily"},
+	}
+	for _, tt := range tests {
+		if got := Describe(tt.template, view); got != tt.want {
+			t.Errorf("Describe(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestOrderByDependencies(t *testing.T) {
+	view := func(schema, name, query string) ViewInfo {
+		return ViewInfo{Database: schema, ViewName: name,
+			CreateSQL: "CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `" + name + "` AS " + query,
+			AlterSQL:  "ALTER VIEW `" + name + "` AS " + query}
+	}
+	views := []ViewInfo{
+		view("app", "top", "select `mid`.`id` AS `id` from `mid`"),
+		view("app", "mid", "select `b`.`id` AS `id` from (`app`.`base` `b` join `rpt`.`other` `o`)"),
+		view("app", "base", "select `t`.`id` AS `id` from `t`"),
+		view("rpt", "other", "select 1 AS `one`"),
+		view("app", "solo", "select 'top' AS `name`"),
+	}
+	ordered, err := OrderByDependencies(views)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range ordered {
+		got = append(got, v.Database+"."+v.ViewName)
+	}
+	want := "app.base rpt.other app.mid app.top app.solo"
+	if strings.Join(got, " ") != want {
+		t.Errorf("OrderByDependencies() = %v, want %s", got, want)
+	}
+
+	cyclic := []ViewInfo{
+		view("app", "a", "select `b`.`x` AS `x` from `b`"),
+		view("app", "b", "select `a`.`x` AS `x` from `a`"),
+	}
+	if _, err := OrderByDependencies(cyclic); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("OrderByDependencies() error = %v, want a cycle error", err)
+	}
+}
+
+func TestOrderByDependenciesIgnoresAliases(t *testing.T) {
+	// Each view has a column named after the other, but neither selects from it
+	views := []ViewInfo{
+		{Database: "app", ViewName: "customers",
+			CreateSQL: "CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `customers` AS select `c`.`id` AS `id`,count(`o`.`id`) AS `orders` from (`app`.`customer` `c` left join `app`.`order` `o` on((`o`.`customer_id` = `c`.`id`))) group by `c`.`id`"},
+		{Database: "app", ViewName: "orders",
+			CreateSQL: "CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `orders` AS select `o`.`id` AS `id`,`c`.`name` AS `customers` from (`app`.`order` `o` join `app`.`customer` `c`) where (`c`.`id` = `o`.`customer_id`)"},
+	}
+	ordered, err := OrderByDependencies(views)
+	if err != nil {
+		t.Fatalf("OrderByDependencies() error: %v", err)
+	}
+	if len(ordered) != 2 || ordered[0].ViewName != "customers" || ordered[1].ViewName != "orders" {
+		t.Errorf("OrderByDependencies() = %v, want the input order", ordered)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	views := []ViewInfo{
+		// The column top is not a reference to the view top
+		{Database: "app", ViewName: "base", CreateSQL: "CREATE VIEW `base` AS select 1 AS `x`,2 AS `top`", AlterSQL: "ALTER ALGORITHM = MERGE VIEW `app`.`base` AS select 1 AS `x`,2 AS `top`;"},
+		{Database: "app", ViewName: "top", CreateSQL: "CREATE VIEW `top` AS select `x` from `base`", AlterSQL: "ALTER ALGORITHM = MERGE VIEW `app`.`top` AS select `x` from `base`;"},
+	}
+	now := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		target string
+		files  []string
+	}{
+		{TargetFlyway, []string{"app/U9__alter_view_app_base.sql", "app/V9__alter_view_app_base.sql", "app/U10__alter_view_app_top.sql", "app/V10__alter_view_app_top.sql"}},
+		{TargetLiquibase, []string{"app/000009__alter_view_app_base.sql", "app/000010__alter_view_app_top.sql"}},
+		{TargetGolangMigrate, []string{"000009_alter_view_app_base.up.sql", "000009_alter_view_app_base.down.sql", "000010_alter_view_app_top.up.sql", "000010_alter_view_app_top.down.sql"}},
+		{TargetSqitch, []string{"deploy/alter_view_app_base.sql", "revert/alter_view_app_base.sql", "verify/alter_view_app_base.sql",
+			"deploy/alter_view_app_top.sql", "revert/alter_view_app_top.sql", "verify/alter_view_app_top.sql", "sqitch.plan"}},
+		{TargetBundle, []string{"views.sql", "views_rollback.sql"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			dir := t.TempDir()
+			target, err := NewTarget(tt.target, TargetOptions{Author: "Jo Doe <jo@example.com>", Project: "views", Now: now})
+			if err != nil {
+				t.Fatal(err)
+			}
+			vr, err := NewVersioner(VersionSequential, "9", "", now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			migrations, problems, err := PlanMigrations(views, vr, DefaultDescription, sqlparse.FormatOptions{})
+			if err != nil || len(problems) > 0 {
+				t.Fatal(err, problems)
+			}
+			written, err := target.Write(dir, migrations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, path := range written {
+				rel, _ := filepath.Rel(dir, path)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.files, " ") {
+				t.Errorf("wrote %v, want %v", got, tt.files)
+			}
+		})
+	}
+
+	// sqitch requires the changes a view depends on, and refuses duplicate changes
+	dir := t.TempDir()
+	target, _ := NewTarget(TargetSqitch, TargetOptions{Author: "Jo Doe <jo@example.com>", Now: now})
+	vr, _ := NewVersioner(VersionSequential, "1", "", now)
+	migrations, _, _ := PlanMigrations(views, vr, DefaultDescription, sqlparse.FormatOptions{})
+	if _, err := target.Write(dir, migrations); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := os.ReadFile(filepath.Join(dir, "sqitch.plan"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(plan), "\nalter_view_app_base 2024-03-05T14:30:00Z ") {
+		t.Errorf("sqitch.plan requires a view for alter_view_app_base:\n%s", plan)
+	}
+	want := "alter_view_app_top [alter_view_app_base] 2024-03-05T14:30:00Z Jo Doe <jo@example.com> # Change ALGORITHM of view app.top\n"
+	if !strings.HasPrefix(string(plan), "%syntax-version=1.0.0\n%project=views\n") || !strings.HasSuffix(string(plan), want) {
+		t.Errorf("sqitch.plan =\n%s", plan)
+	}
+	if _, err := target.Write(dir, migrations); err == nil {
+		t.Error("writing the same changes twice did not fail")
+	}
+}
+
+func TestPlanMigrationsProblems(t *testing.T) {
+	views := []ViewInfo{
+		{Database: "app", ViewName: "ok", Algorithm: "UNDEFINED", NewAlgorithm: "MERGE", CreateSQL: "CREATE VIEW `ok` AS select 1 AS `x`", AlterSQL: "ALTER ALGORITHM = MERGE VIEW `app`.`ok` AS select 1 AS `x`;"},
+		{Database: "app", ViewName: "ok", Algorithm: "UNDEFINED", NewAlgorithm: "MERGE", CreateSQL: "CREATE VIEW `ok` AS select 1 AS `x`", AlterSQL: "ALTER ALGORITHM = MERGE VIEW `app`.`ok` AS select 1 AS `x`;"},
+		{Database: "app", ViewName: "same", Algorithm: "MERGE", NewAlgorithm: "MERGE", CreateSQL: "CREATE VIEW `same` AS select 1 AS `x`", AlterSQL: "ALTER ALGORITHM = MERGE VIEW `app`.`same` AS select 1 AS `x`;"},
+		{Database: "app", ViewName: "empty", CreateSQL: "CREATE VIEW `empty` AS select 1 AS `x`"},
+		{Database: "app", ViewName: "nocreate", AlterSQL: "ALTER ALGORITHM = MERGE VIEW `app`.`nocreate` AS select 1 AS `x`;"},
+	}
+	vr, _ := NewVersioner(VersionSequential, "1", "", time.Now())
+	migrations, problems, err := PlanMigrations(views, vr, DefaultDescription, sqlparse.FormatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 1 || migrations[0].View.ViewName != "ok" || migrations[0].Version.String() != "1" {
+		t.Errorf("PlanMigrations() = %v, want only app.ok as version 1", migrations)
+	}
+
+	want := []struct {
+		name    string
+		skipped bool
+		reason  string
+	}{
+		{"ok", true, "duplicate"},
+		{"same", true, "already uses ALGORITHM MERGE"},
+		{"empty", false, "no ALTER statement"},
+		{"nocreate", false, "CREATE statement was not captured"},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("PlanMigrations() problems = %v, want %d", problems, len(want))
+	}
+	for i, w := range want {
+		p := problems[i]
+		if p.View.ViewName != w.name || p.Skipped != w.skipped || !strings.Contains(p.Reason, w.reason) {
+			t.Errorf("problem %d = %s skipped=%v %q, want %s skipped=%v %q", i, p.View.ViewName, p.Skipped, p.Reason, w.name, w.skipped, w.reason)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package viewformat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/deps"
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// viewKey identifies a view across schemas. MySQL may fold table name case,
+// so names are compared without it.
+func viewKey(schema, name string) string {
+	return strings.ToLower(schema) + "." + strings.ToLower(name)
+}
+
+// References returns the schema-qualified keys of the tables and views the
+// query of a view selects from, as sqlparse.ReferencedTables finds them.
+// Unqualified names are resolved in the view's own schema. Column names and
+// aliases are not references, even when they share a view's name.
+func References(view ViewInfo) ([]string, error) {
+	stmt := view.CreateSQL
+	if stmt == "" {
+		stmt = view.AlterSQL
+	}
+	parsed, err := sqlparse.ParseCreateView(stmt)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := sqlparse.ReferencedTables(parsed.Select)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]string, len(tables))
+	for i, table := range tables {
+		if schema, name, ok := strings.Cut(table, "."); ok {
+			refs[i] = viewKey(schema, name)
+		} else {
+			refs[i] = viewKey(view.Database, table)
+		}
+	}
+	return refs, nil
+}
+
+// OrderByDependencies sorts views so that every view comes after the views
+// of the same batch it selects from. Otherwise the input order is kept. A
+// dependency cycle is an error.
+func OrderByDependencies(views []ViewInfo) ([]ViewInfo, error) {
+	g := deps.NewGraph()
+	nodes := make([]deps.Node, len(views))
+	index := make(map[string]int, len(views))
+	for i, view := range views {
+		nodes[i] = deps.Node{Schema: view.Database, Name: view.ViewName, Type: "VIEW"}
+		g.AddNode(nodes[i])
+		index[viewKey(view.Database, view.ViewName)] = i
+	}
+	for i, view := range views {
+		refs, err := References(view)
+		if err != nil {
+			return nil, fmt.Errorf("view %s.%s: %v", view.Database, view.ViewName, err)
+		}
+		for _, ref := range refs {
+			if j, ok := index[ref]; ok {
+				g.AddEdge(nodes[i], nodes[j])
+			}
+		}
+	}
+
+	sorted, err := g.Order(nodes)
+	if cycle, ok := err.(*deps.CycleError); ok {
+		names := make([]string, len(cycle.Nodes))
+		for i, n := range cycle.Nodes {
+			names[i] = n.String()
+		}
+		return nil, fmt.Errorf("dependency cycle between views: %s", strings.Join(names, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	ordered := make([]ViewInfo, len(sorted))
+	for i, n := range sorted {
+		ordered[i] = views[index[viewKey(n.Schema, n.Name)]]
+	}
+	return ordered, nil
+}
@@ -0,0 +1,164 @@
+// Package viewformat turns captured ALTER VIEW statements into migration
+// scripts.
+package viewformat
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// ViewInfo is one view with its original and new definition. The JSON keys
+// are those of the go-pvt -algo -o json/ndjson view export.
+type ViewInfo struct {
+	Server              string `json:"host"`
+	Database            string `json:"schema"`
+	ViewName            string `json:"name"`
+	Definer             string `json:"definer"`
+	Algorithm           string `json:"algorithm"`     // algorithm before the change
+	NewAlgorithm        string `json:"new_algorithm"` // algorithm AlterSQL sets
+	CharacterSetClient  string `json:"character_set_client"`
+	CollationConnection string `json:"collation_connection"`
+	CreateSQL           string `json:"create_statement"`
+	AlterSQL            string `json:"alter_statement"`
+}
+
+// ansiEscape matches the colour codes of output captured from a terminal
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// discardLogger is used when the caller passes no logger
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// ParseViews reconstructs views from captured go-pvt -show-create -algo
+// output. Each parsed view is logged at debug level to logger, which may be
+// nil. This is the legacy input format; ReadViews prefers the JSON export
+// when it is given one.
+func ParseViews(r io.Reader, logger *slog.Logger) ([]ViewInfo, error) {
+	if logger == nil {
+		logger = discardLogger
+	}
+	var views []ViewInfo
+	scanner := bufio.NewScanner(r)
+	// View definitions easily exceed bufio's default 64 KiB line limit
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var currentServer, currentDatabase string
+	var currentView ViewInfo
+	var inCreateSection, inAlterSection bool
+	var createSQL, alterSQL strings.Builder
+	var createSeparatorSeen, alterSeparatorSeen bool
+	var waitForCreateSeparator, waitForAlterSeparator bool
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(ansiEscape.ReplaceAllString(scanner.Text(), ""))
+
+		// Parse server information
+		if strings.HasPrefix(line, "Source server:") {
+			parts := strings.Split(line, ",")
+			if len(parts) >= 2 {
+				currentServer = strings.TrimSpace(strings.TrimPrefix(parts[0], "Source server:"))
+				dbPart := strings.TrimSpace(parts[1])
+				currentDatabase = strings.TrimPrefix(dbPart, "Database: ")
+			}
+			continue
+		}
+
+		// Parse view processing line
+		if strings.HasPrefix(line, "Processing view:") {
+			// Save previous view if exists
+			if currentView.ViewName != "" {
+				currentView.CreateSQL = strings.TrimSpace(createSQL.String())
+				currentView.AlterSQL = strings.TrimSpace(alterSQL.String())
+				logger.Debug("parsed view", "schema", currentView.Database, "name", currentView.ViewName,
+					"create_bytes", len(currentView.CreateSQL), "alter_bytes", len(currentView.AlterSQL))
+				views = append(views, currentView)
+			}
+
+			// Start new view
+			currentView = ViewInfo{
+				Database: currentDatabase,
+				ViewName: strings.TrimSpace(strings.TrimPrefix(line, "Processing view:")),
+				Server:   currentServer,
+			}
+			createSQL.Reset()
+			alterSQL.Reset()
+			inCreateSection = false
+			inAlterSection = false
+			createSeparatorSeen = false
+			alterSeparatorSeen = false
+			waitForCreateSeparator = false
+			waitForAlterSeparator = false
+			continue
+		}
+
+		// Parse CREATE statement section
+		if line == "Create Statement:" {
+			inCreateSection = true
+			inAlterSection = false
+			waitForCreateSeparator = true // Need to wait for separator before capturing SQL
+			continue
+		}
+
+		// Parse ALTER statement section
+		if line == "ALTER VIEW Statement:" {
+			inCreateSection = false
+			inAlterSection = true
+			waitForAlterSeparator = true // Need to wait for separator before capturing SQL
+			continue
+		}
+
+		// Handle separator lines
+		if strings.HasPrefix(line, "---") {
+			if inCreateSection && waitForCreateSeparator {
+				waitForCreateSeparator = false
+				createSeparatorSeen = true
+				continue
+			} else if inCreateSection && createSeparatorSeen {
+				inCreateSection = false
+				continue
+			} else if inAlterSection && waitForAlterSeparator {
+				waitForAlterSeparator = false
+				alterSeparatorSeen = true
+				continue
+			} else if inAlterSection && alterSeparatorSeen {
+				inAlterSection = false
+				continue
+			}
+			continue
+		}
+
+		// Skip other metadata lines
+		if strings.HasPrefix(line, "Connected to") || strings.HasPrefix(line, "Object:") || strings.HasPrefix(line, "Type:") {
+			continue
+		}
+
+		// Collect CREATE SQL - after first separator but before second
+		if inCreateSection && createSeparatorSeen && line != "" {
+			if createSQL.Len() > 0 {
+				createSQL.WriteString("\n")
+			}
+			createSQL.WriteString(line)
+		}
+
+		// Collect ALTER SQL - after first separator but before second
+		if inAlterSection && alterSeparatorSeen && line != "" {
+			if alterSQL.Len() > 0 {
+				alterSQL.WriteString("\n")
+			}
+			alterSQL.WriteString(line)
+		}
+	}
+
+	// Don't forget the last view
+	if currentView.ViewName != "" {
+		currentView.CreateSQL = strings.TrimSpace(createSQL.String())
+		currentView.AlterSQL = strings.TrimSpace(alterSQL.String())
+		logger.Debug("parsed view", "schema", currentView.Database, "name", currentView.ViewName,
+			"create_bytes", len(currentView.CreateSQL), "alter_bytes", len(currentView.AlterSQL))
+		views = append(views, currentView)
+	}
+
+	return views, scanner.Err()
+}
@@ -0,0 +1,283 @@
+package viewformat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// Target writes planned migrations in the layout of one migration tool
+type Target interface {
+	// Write writes the files of migrations under dir and returns their paths
+	Write(dir string, migrations []Migration) ([]string, error)
+}
+
+// TargetOptions carries the settings some targets need besides the migrations
+type TargetOptions struct {
+	Author  string    // "Name <email>", used by liquibase and sqitch
+	Project string    // sqitch project name for a new sqitch.plan
+	Now     time.Time // planning time recorded in sqitch.plan
+}
+
+// Target names accepted by NewTarget
+const (
+	TargetFlyway        = "flyway"
+	TargetLiquibase     = "liquibase"
+	TargetGolangMigrate = "golang-migrate"
+	TargetSqitch        = "sqitch"
+	TargetBundle        = "bundle"
+)
+
+// TargetNames lists the supported targets
+var TargetNames = []string{TargetFlyway, TargetLiquibase, TargetGolangMigrate, TargetSqitch, TargetBundle}
+
+// NewTarget returns the writer for a migration tool
+func NewTarget(name string, opts TargetOptions) (Target, error) {
+	switch name {
+	case TargetFlyway:
+		return flywayTarget{}, nil
+	case TargetLiquibase:
+		return liquibaseTarget{author: authorName(opts.Author)}, nil
+	case TargetGolangMigrate:
+		return golangMigrateTarget{}, nil
+	case TargetSqitch:
+		return sqitchTarget{opts: opts}, nil
+	case TargetBundle:
+		return bundleTarget{}, nil
+	}
+	return nil, fmt.Errorf("invalid target: %s. Must be one of %s", name, strings.Join(TargetNames, ", "))
+}
+
+// IntegerVersions reports whether a target needs versions without dots
+func IntegerVersions(target string) bool {
+	return target == TargetGolangMigrate
+}
+
+// writeFile creates the parent directories of path and writes content to it
+func writeFile(path, content string, written *[]string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	*written = append(*written, path)
+	return nil
+}
+
+// authorName returns the name part of "Name <email>"
+func authorName(author string) string {
+	if i := strings.Index(author, "<"); i != -1 {
+		return strings.TrimSpace(author[:i])
+	}
+	return strings.TrimSpace(author)
+}
+
+// flywayTarget writes V<version>__<description>.sql and the matching U undo
+// migration under dir/<database>
+type flywayTarget struct{}
+
+func (flywayTarget) Write(dir string, migrations []Migration) ([]string, error) {
+	var written []string
+	for _, m := range migrations {
+		dbDir := filepath.Join(dir, m.View.Database)
+		if err := writeFile(filepath.Join(dbDir, m.UndoFile()), FormatUFile(m), &written); err != nil {
+			return written, err
+		}
+		if err := writeFile(filepath.Join(dbDir, m.VersionedFile()), FormatVFile(m), &written); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// liquibaseTarget writes one formatted SQL changelog per view under
+// dir/<database>, suitable for includeAll. includeAll orders files by name,
+// so the versions in the names are zero-padded.
+type liquibaseTarget struct {
+	author string
+}
+
+func (t liquibaseTarget) Write(dir string, migrations []Migration) ([]string, error) {
+	var written []string
+	for _, m := range migrations {
+		var b strings.Builder
+		b.WriteString("--liquibase formatted sql\n\n")
+		fmt.Fprintf(&b, "--changeset %s:%s-%s splitStatements:false\n", t.author, m.Version, m.Description)
+		fmt.Fprintf(&b, "--comment: Change ALGORITHM of view %s.%s\n", m.View.Database, m.View.ViewName)
+		b.WriteString(m.Up + "\n")
+		for _, line := range strings.Split(m.Down, "\n") {
+			b.WriteString("--rollback " + line + "\n")
+		}
+		name := fmt.Sprintf("%s__%s.sql", m.Version.Padded(6), m.Description)
+		if err := writeFile(filepath.Join(dir, m.View.Database, name), b.String(), &written); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// golangMigrateTarget writes NNNNNN_<description>.up.sql and .down.sql
+// pairs directly in dir, since golang-migrate does not read subdirectories
+type golangMigrateTarget struct{}
+
+func (golangMigrateTarget) Write(dir string, migrations []Migration) ([]string, error) {
+	for _, m := range migrations {
+		if len(m.Version) != 1 {
+			return nil, fmt.Errorf("golang-migrate needs integer versions, got %s", m.Version)
+		}
+	}
+	var written []string
+	for _, m := range migrations {
+		base := filepath.Join(dir, fmt.Sprintf("%s_%s", m.Version.Padded(6), m.Description))
+		up := fmt.Sprintf("-- Database: %s\n-- View: %s\n\n%s\n", m.View.Database, m.View.ViewName, m.Up)
+		if err := writeFile(base+".up.sql", up, &written); err != nil {
+			return written, err
+		}
+		down := fmt.Sprintf("-- Database: %s\n-- View: %s\n\n%s\n", m.View.Database, m.View.ViewName, m.Down)
+		if err := writeFile(base+".down.sql", down, &written); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// sqitchTarget writes deploy, revert and verify scripts and appends the
+// changes to dir/sqitch.plan, creating it if needed. Views that select from
+// other views of the batch require those changes.
+type sqitchTarget struct {
+	opts TargetOptions
+}
+
+func (t sqitchTarget) Write(dir string, migrations []Migration) ([]string, error) {
+	planPath := filepath.Join(dir, "sqitch.plan")
+	existing, err := sqitchChanges(planPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Change names are the descriptions, and must be unique within the plan
+	names := make(map[string]string, len(migrations))
+	for _, m := range migrations {
+		if existing[m.Description] {
+			return nil, fmt.Errorf("change %s is already in %s; use a different description template", m.Description, planPath)
+		}
+		names[viewKey(m.View.Database, m.View.ViewName)] = m.Description
+	}
+
+	var plan strings.Builder
+	if _, err := os.Stat(planPath); os.IsNotExist(err) {
+		project := t.opts.Project
+		if project == "" {
+			project = "views"
+		}
+		fmt.Fprintf(&plan, "%%syntax-version=1.0.0\n%%project=%s\n\n", project)
+	}
+
+	var written []string
+	now := t.opts.Now.UTC().Format("2006-01-02T15:04:05Z")
+	for _, m := range migrations {
+		view := m.View
+		header := func(action string) string {
+			return fmt.Sprintf("-- %s %s\n-- Database: %s\n-- View: %s\n\n", action, m.Description, view.Database, view.ViewName)
+		}
+		if err := writeFile(filepath.Join(dir, "deploy", m.Description+".sql"), header("Deploy")+m.Up+"\n", &written); err != nil {
+			return written, err
+		}
+		if err := writeFile(filepath.Join(dir, "revert", m.Description+".sql"), header("Revert")+m.Down+"\n", &written); err != nil {
+			return written, err
+		}
+		// Selecting no rows fails if the view is missing or no longer valid
+		verify := fmt.Sprintf("SELECT 1 FROM %s.%s WHERE 0;\n", sqlparse.QuoteIdentifier(view.Database), sqlparse.QuoteIdentifier(view.ViewName))
+		if err := writeFile(filepath.Join(dir, "verify", m.Description+".sql"), header("Verify")+verify, &written); err != nil {
+			return written, err
+		}
+
+		var requires []string
+		if refs, err := References(view); err == nil {
+			for _, ref := range refs {
+				if name, ok := names[ref]; ok && name != m.Description {
+					requires = append(requires, name)
+				}
+			}
+		}
+		sort.Strings(requires)
+		plan.WriteString(m.Description)
+		if len(requires) > 0 {
+			plan.WriteString(" [" + strings.Join(requires, " ") + "]")
+		}
+		fmt.Fprintf(&plan, " %s %s # Change ALGORITHM of view %s.%s\n", now, t.opts.Author, view.Database, view.ViewName)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return written, err
+	}
+	file, err := os.OpenFile(planPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return written, err
+	}
+	if _, err := file.WriteString(plan.String()); err != nil {
+		file.Close()
+		return written, err
+	}
+	if err := file.Close(); err != nil {
+		return written, err
+	}
+	return append(written, planPath), nil
+}
+
+// sqitchChanges returns the names of the changes already in a sqitch.plan
+func sqitchChanges(path string) (map[string]bool, error) {
+	changes := make(map[string]bool)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return changes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip pragmas, comments and tags
+		if line == "" || line[0] == '%' || line[0] == '#' || line[0] == '@' {
+			continue
+		}
+		changes[strings.Fields(line)[0]] = true
+	}
+	return changes, scanner.Err()
+}
+
+// bundleTarget writes every change to dir/views.sql and every rollback, in
+// reverse order, to dir/views_rollback.sql
+type bundleTarget struct{}
+
+func (bundleTarget) Write(dir string, migrations []Migration) ([]string, error) {
+	var up, down strings.Builder
+	up.WriteString("-- View algorithm changes, in dependency order\n")
+	down.WriteString("-- Rollback of views.sql, in reverse dependency order\n")
+	for _, m := range migrations {
+		fmt.Fprintf(&up, "\n-- %s %s: %s.%s\n%s\n", m.Version, m.Description, m.View.Database, m.View.ViewName, m.Up)
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		fmt.Fprintf(&down, "\n-- %s %s: %s.%s\n%s\n", m.Version, m.Description, m.View.Database, m.View.ViewName, m.Down)
+	}
+
+	var written []string
+	if err := writeFile(filepath.Join(dir, "views.sql"), up.String(), &written); err != nil {
+		return written, err
+	}
+	if err := writeFile(filepath.Join(dir, "views_rollback.sql"), down.String(), &written); err != nil {
+		return written, err
+	}
+	return written, nil
+}
@@ -0,0 +1,78 @@
+package viewformat
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+// originalView parses the captured CREATE statement of a view, qualified with its database
+func originalView(view ViewInfo) (*sqlparse.CreateView, error) {
+	if strings.TrimSpace(view.CreateSQL) == "" {
+		return nil, fmt.Errorf("the original CREATE statement was not captured")
+	}
+	parsed, err := sqlparse.ParseCreateView(view.CreateSQL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the original CREATE statement: %v", err)
+	}
+	// SHOW CREATE VIEW leaves the name unqualified
+	if parsed.Schema == "" {
+		parsed.Schema = view.Database
+	}
+	return parsed, nil
+}
+
+// UndoSQL returns a CREATE OR REPLACE VIEW that restores the definition the
+// view had before the change: its algorithm, definer, SQL SECURITY, columns,
+// query and check option exactly as captured in CreateSQL. The query is not
+// reformatted, so string literals and comments in it survive unchanged.
+func UndoSQL(view ViewInfo) (string, error) {
+	parsed, err := originalView(view)
+	if err != nil {
+		return "", err
+	}
+	return parsed.CreateOrReplaceStatement(), nil
+}
+
+// VerifyUndo checks that undo, which may carry comments, recreates exactly
+// the view captured in CreateSQL
+func VerifyUndo(view ViewInfo, undo string) error {
+	want, err := originalView(view)
+	if err != nil {
+		return err
+	}
+	got, err := sqlparse.ParseCreateView(undo)
+	if err != nil {
+		return fmt.Errorf("undo script does not parse: %v", err)
+	}
+	if got.Verb != "CREATE OR REPLACE" {
+		return fmt.Errorf("undo script is %s VIEW, want CREATE OR REPLACE VIEW", got.Verb)
+	}
+	got.Verb = want.Verb
+
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("undo script does not restore the original definition: %s", describeDifference(got, want))
+	}
+	return nil
+}
+
+// describeDifference names the first clause that differs between two views
+func describeDifference(got, want *sqlparse.CreateView) string {
+	switch {
+	case got.Algorithm != want.Algorithm:
+		return fmt.Sprintf("ALGORITHM %s, want %s", got.Algorithm, want.Algorithm)
+	case got.Definer() != want.Definer():
+		return fmt.Sprintf("DEFINER %s, want %s", got.Definer(), want.Definer())
+	case got.Security != want.Security:
+		return fmt.Sprintf("SQL SECURITY %s, want %s", got.Security, want.Security)
+	case got.QualifiedName() != want.QualifiedName():
+		return fmt.Sprintf("view %s, want %s", got.QualifiedName(), want.QualifiedName())
+	case !reflect.DeepEqual(got.Columns, want.Columns):
+		return fmt.Sprintf("columns %v, want %v", got.Columns, want.Columns)
+	case got.CheckOption != want.CheckOption:
+		return fmt.Sprintf("check option %q, want %q", got.CheckOption, want.CheckOption)
+	}
+	return "the query differs"
+}
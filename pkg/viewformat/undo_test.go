@@ -0,0 +1,84 @@
+package viewformat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChaosHour/go-pvt/pkg/sqlparse"
+)
+
+func TestUndoRestoresOriginal(t *testing.T) {
+	tests := []struct {
+		name string
+		view ViewInfo
+		want string
+	}{
+		{
+			name: "temptable with invoker security",
+			view: ViewInfo{
+				Database:  "app",
+				ViewName:  "v_alter",
+				CreateSQL: "CREATE ALGORITHM=TEMPTABLE DEFINER=`old`@`10.%` SQL SECURITY INVOKER VIEW `v_alter` AS select `t`.`ALTER_flag` AS `ALTER_flag`,'ALGORITHM = MERGE  kept' AS `note` from `t`",
+				AlterSQL:  "ALTER ALGORITHM = MERGE DEFINER=`new`@`%` SQL SECURITY DEFINER VIEW `app`.`v_alter` AS select `t`.`ALTER_flag` AS `ALTER_flag`,'ALGORITHM = MERGE  kept' AS `note` from `t`;",
+			},
+			want: "CREATE OR REPLACE \n    ALGORITHM = TEMPTABLE\n    DEFINER=`old`@`10.%`\n    SQL SECURITY INVOKER\n    VIEW `app`.`v_alter` AS select `t`.`ALTER_flag` AS `ALTER_flag`,'ALGORITHM = MERGE  kept' AS `note` from `t`;",
+		},
+		{
+			name: "columns and check option",
+			view: ViewInfo{
+				Database:  "app",
+				ViewName:  "v2",
+				CreateSQL: "CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `v2` (`a`,`b`) AS select 1,2 WITH LOCAL CHECK OPTION",
+				AlterSQL:  "ALTER ALGORITHM = MERGE VIEW `app`.`v2` (`a`,`b`) AS select 1,2 WITH LOCAL CHECK OPTION;",
+			},
+			want: "CREATE OR REPLACE \n    ALGORITHM = UNDEFINED\n    DEFINER=`app`@`%`\n    SQL SECURITY DEFINER\n    VIEW `app`.`v2` (`a`,`b`) AS select 1,2 WITH LOCAL CHECK OPTION;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vr, _ := NewVersioner(VersionSequential, "1", "", time.Now())
+			migrations, problems, err := PlanMigrations([]ViewInfo{tt.view}, vr, DefaultDescription, sqlparse.FormatOptions{})
+			if err != nil || len(problems) > 0 {
+				t.Fatal(err, problems)
+			}
+			m := migrations[0]
+			if m.Down != tt.want {
+				t.Errorf("Down =\n%s\nwant\n%s", m.Down, tt.want)
+			}
+			// The whole U file, comments included, must round-trip
+			if err := VerifyUndo(tt.view, FormatUFile(m)); err != nil {
+				t.Errorf("VerifyUndo(U file): %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyUndoDetectsDifferences(t *testing.T) {
+	view := ViewInfo{
+		Database:  "app",
+		ViewName:  "v",
+		CreateSQL: "CREATE ALGORITHM=TEMPTABLE DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `v` AS select 1 AS `x`",
+	}
+	tests := []struct {
+		undo string
+		want string
+	}{
+		{"CREATE OR REPLACE ALGORITHM = UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `app`.`v` AS select 1 AS `x`;", "ALGORITHM"},
+		{"CREATE OR REPLACE ALGORITHM = TEMPTABLE DEFINER=`other`@`%` SQL SECURITY DEFINER VIEW `app`.`v` AS select 1 AS `x`;", "DEFINER"},
+		{"CREATE OR REPLACE ALGORITHM = TEMPTABLE DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `other`.`v` AS select 1 AS `x`;", "view"},
+		{"CREATE OR REPLACE ALGORITHM = TEMPTABLE DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `app`.`v` AS select 2 AS `x`;", "query"},
+		{"ALTER ALGORITHM = TEMPTABLE DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `app`.`v` AS select 1 AS `x`;", "CREATE OR REPLACE"},
+	}
+	for _, tt := range tests {
+		err := VerifyUndo(view, tt.undo)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("VerifyUndo(%q) = %v, want an error mentioning %s", tt.undo, err, tt.want)
+		}
+	}
+
+	if _, err := UndoSQL(ViewInfo{Database: "app", ViewName: "v"}); err == nil {
+		t.Error("UndoSQL without CreateSQL did not fail")
+	}
+}
@@ -0,0 +1,194 @@
+package viewformat
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version numbering schemes for migration files
+const (
+	VersionTimestamp  = "timestamp"  // <yyyyMMddHHmmss>.<n>, unique per run
+	VersionSequential = "sequential" // Start, Start+1, ...
+	VersionScan       = "scan"       // continue after the highest version in a directory
+)
+
+// Version is a Flyway version such as 1, 1.2 or 20261016120000.3
+type Version []int
+
+// ParseVersion parses a Flyway version. Like Flyway, it accepts both dots
+// and underscores as separators.
+func ParseVersion(s string) (Version, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty version")
+	}
+	var v Version
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == '.' || r == '_' }) {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		v = append(v, n)
+	}
+	if len(v) == 0 {
+		return nil, fmt.Errorf("invalid version %q", s)
+	}
+	return v, nil
+}
+
+// String renders the version with dots
+func (v Version) String() string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Padded renders the version with dots and every part zero-padded to width,
+// so that versions sort correctly by name
+func (v Version) Padded(width int) string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = fmt.Sprintf("%0*d", width, n)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Compare returns -1, 0 or 1. Missing trailing parts count as zero, as in Flyway.
+func (v Version) Compare(other Version) int {
+	for i := 0; i < len(v) || i < len(other); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(other) {
+			b = other[i]
+		}
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Next increments the last part of the version, so 5 becomes 6 and 1.2 becomes 1.3
+func (v Version) Next() Version {
+	next := append(Version{}, v...)
+	next[len(next)-1]++
+	return next
+}
+
+// versionedFile matches Flyway versioned and undo migration file names, and
+// golang-migrate up and down migrations
+var versionedFile = regexp.MustCompile(`^(?:[VU]([0-9][0-9._]*)__.*|([0-9]+)_.*\.(?:up|down))\.sql$`)
+
+// HighestVersion returns the highest version of the migrations under dir,
+// searching subdirectories like Flyway does. It returns nil if there are none.
+func HighestVersion(dir string) (Version, error) {
+	var highest Version
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		m := versionedFile.FindStringSubmatch(d.Name())
+		if m == nil {
+			return nil
+		}
+		v, err := ParseVersion(strings.TrimRight(m[1]+m[2], "._"))
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if highest == nil || v.Compare(highest) > 0 {
+			highest = v
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return highest, err
+}
+
+// Versioner hands out consecutive migration versions
+type Versioner struct {
+	next Version
+}
+
+// NewVersioner returns a Versioner for scheme. start is the first version of
+// the sequential scheme, dir the migrations directory the scan scheme reads
+// and now the time the timestamp scheme uses.
+func NewVersioner(scheme, start, dir string, now time.Time) (*Versioner, error) {
+	switch scheme {
+	case VersionTimestamp:
+		ts, err := strconv.Atoi(TimestampVersion(now))
+		if err != nil {
+			return nil, err
+		}
+		return &Versioner{next: Version{ts, 1}}, nil
+	case VersionSequential:
+		v, err := ParseVersion(start)
+		if err != nil {
+			return nil, err
+		}
+		return &Versioner{next: v}, nil
+	case VersionScan:
+		highest, err := HighestVersion(dir)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning %s: %v", dir, err)
+		}
+		if highest == nil {
+			v, err := ParseVersion(start)
+			if err != nil {
+				return nil, err
+			}
+			return &Versioner{next: v}, nil
+		}
+		return &Versioner{next: highest.Next()}, nil
+	}
+	return nil, fmt.Errorf("invalid version scheme: %s. Must be %s, %s or %s", scheme, VersionTimestamp, VersionSequential, VersionScan)
+}
+
+// NewIntegerTimestampVersioner counts up from the yyyyMMddHHmmss of now, for
+// tools whose versions are plain integers. When an earlier run already used
+// that version, or a later one, numbering continues after the highest version
+// under dir instead.
+func NewIntegerTimestampVersioner(dir string, now time.Time) (*Versioner, error) {
+	ts, err := strconv.Atoi(TimestampVersion(now))
+	if err != nil {
+		return nil, err
+	}
+	highest, err := HighestVersion(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning %s: %v", dir, err)
+	}
+	next := Version{ts}
+	if highest != nil && highest.Compare(next) >= 0 {
+		next = Version{highest[0] + 1}
+	}
+	return &Versioner{next: next}, nil
+}
+
+// TimestampVersion returns now as a single yyyyMMddHHmmss version, for tools
+// such as golang-migrate whose versions are plain integers
+func TimestampVersion(now time.Time) string {
+	return now.UTC().Format("20060102150405")
+}
+
+// Next returns the next version
+func (vr *Versioner) Next() Version {
+	v := vr.next
+	vr.next = v.Next()
+	return v
+}